@@ -0,0 +1,244 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff computes the delay to wait before retrying an operation that has
+// already failed attempt times in a row (attempt starts at 0 for the first
+// retry), and is reset once the operation succeeds.
+type Backoff interface {
+	// Duration returns how long to wait before the given attempt.
+	Duration(attempt int) time.Duration
+}
+
+// ExponentialBackoff is the default Backoff used by Reader when
+// ReaderConfig.Backoff is left unset: delays double after every attempt,
+// up to Max, and are jittered to avoid every reader in a group retrying in
+// lockstep.
+type ExponentialBackoff struct {
+	// Init is the delay used for the first attempt. Defaults to 100ms.
+	Init time.Duration
+	// Factor is the multiplier applied to the delay after each attempt.
+	// Defaults to 2.
+	Factor float64
+	// Jitter is the fraction of the computed delay that is randomly added
+	// or subtracted, e.g. 0.2 for ±20%. Defaults to 0.2.
+	Jitter float64
+	// Max caps the computed delay. Defaults to 30s.
+	Max time.Duration
+}
+
+// DefaultBackoff is the ExponentialBackoff used by Reader when no Backoff
+// is configured: an initial delay of 100ms, doubling on each attempt,
+// jittered by ±20%, capped at 30s.
+var DefaultBackoff Backoff = &ExponentialBackoff{
+	Init:   100 * time.Millisecond,
+	Factor: 2,
+	Jitter: 0.2,
+	Max:    30 * time.Second,
+}
+
+func (b *ExponentialBackoff) Duration(attempt int) time.Duration {
+	init, factor, jitter, max := b.Init, b.Factor, b.Jitter, b.Max
+	if init <= 0 {
+		init = 100 * time.Millisecond
+	}
+	if factor <= 0 {
+		factor = 2
+	}
+	if jitter <= 0 {
+		jitter = 0.2
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+
+	delay := float64(init)
+	for i := 0; i < attempt; i++ {
+		delay *= factor
+		if delay >= float64(max) {
+			delay = float64(max)
+			break
+		}
+	}
+
+	delta := delay * jitter
+	delay += (rand.Float64()*2 - 1) * delta
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > float64(max) {
+		delay = float64(max)
+	}
+	return time.Duration(delay)
+}
+
+// ReaderState describes the connectivity state of a Reader, as reported to
+// ReaderConfig.OnStateChange and returned by Reader.State.
+type ReaderState int
+
+const (
+	// ReaderStateConnecting is the state of a Reader that has not yet
+	// completed its first successful fetch (or, with GroupID set, its first
+	// successful group join).
+	ReaderStateConnecting ReaderState = iota
+	// ReaderStateRunning is the state of a Reader that is fetching
+	// messages normally.
+	ReaderStateRunning
+	// ReaderStateRecovering is the state of a Reader that has lost its
+	// connection (broker outage, leader election, coordinator move) and is
+	// retrying with backoff.
+	ReaderStateRecovering
+	// ReaderStateStopped is the state of a Reader after Close has been
+	// called.
+	ReaderStateStopped
+)
+
+func (s ReaderState) String() string {
+	switch s {
+	case ReaderStateConnecting:
+		return "connecting"
+	case ReaderStateRunning:
+		return "running"
+	case ReaderStateRecovering:
+		return "recovering"
+	case ReaderStateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// ConnSupervisor wraps a Reader's fetch loop so that broker outages,
+// leader elections, and coordinator moves are retried with backoff instead
+// of surfacing as errors the caller has to loop on itself. It tracks the
+// current ReaderState, invokes ReaderConfig.OnStateChange on every
+// transition, and resets the configured Backoff whenever a fetch succeeds.
+//
+// State is read from Reader.State(), typically by a health-check endpoint
+// running on its own goroutine, while transitions are driven by whichever
+// goroutine runs the fetch loop; both access the same fields, so every
+// method takes the mutex.
+type ConnSupervisor struct {
+	backoff Backoff
+	onState func(old, new ReaderState)
+
+	mutex   sync.Mutex
+	state   ReaderState
+	attempt int
+}
+
+// NewConnSupervisor returns a ConnSupervisor in ReaderStateConnecting. A
+// nil backoff defaults to DefaultBackoff; a nil onState is simply skipped
+// on every transition.
+func NewConnSupervisor(backoff Backoff, onState func(old, new ReaderState)) *ConnSupervisor {
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	return &ConnSupervisor{backoff: backoff, onState: onState, state: ReaderStateConnecting}
+}
+
+func (s *ConnSupervisor) transition(next ReaderState) {
+	s.mutex.Lock()
+	old := s.state
+	if old == next {
+		s.mutex.Unlock()
+		return
+	}
+	s.state = next
+	if next == ReaderStateRunning {
+		s.attempt = 0
+	}
+	s.mutex.Unlock()
+
+	if s.onState != nil {
+		s.onState(old, next)
+	}
+}
+
+// succeeded records a successful fetch: it resets the backoff attempt
+// counter and transitions to ReaderStateRunning.
+func (s *ConnSupervisor) succeeded() {
+	s.transition(ReaderStateRunning)
+}
+
+// failed records a failed fetch, Metadata call, FindCoordinator call, or
+// JoinGroup call, transitions to ReaderStateRecovering, and returns how
+// long the caller should wait before retrying.
+func (s *ConnSupervisor) failed() time.Duration {
+	s.transition(ReaderStateRecovering)
+
+	s.mutex.Lock()
+	delay := s.backoff.Duration(s.attempt)
+	s.attempt++
+	s.mutex.Unlock()
+
+	return delay
+}
+
+// stopped transitions to ReaderStateStopped. Once stopped, the supervisor
+// is done and no further transitions are expected.
+func (s *ConnSupervisor) stopped() {
+	s.transition(ReaderStateStopped)
+}
+
+// State returns the current state without triggering a transition. It is
+// safe to call concurrently with Run, matching how Reader.State() is meant
+// to be polled from a health-check endpoint while the fetch loop runs on
+// its own goroutine.
+func (s *ConnSupervisor) State() ReaderState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state
+}
+
+// errSupervisorStopped is returned by fetch functions passed to Run to
+// signal a deliberate, non-retryable stop (e.g. the Reader was closed),
+// as distinct from a transient failure that should be retried.
+var errSupervisorStopped = errors.New("kafka: supervisor stopped")
+
+// Run repeatedly calls fetch until ctx is done or fetch returns
+// errSupervisorStopped. Every fetch error other than ctx's own
+// cancellation is treated as transient: Run transitions to
+// ReaderStateRecovering, sleeps for the configured Backoff's duration for
+// the current attempt, and calls fetch again. A successful fetch resets
+// the backoff and transitions to ReaderStateRunning. Reader does not call
+// Run: its direct fetch loop, group join loop, and per-partition fetch
+// loops report each outcome through the same succeeded/failed transitions
+// and sleep for the returned delay themselves.
+func (s *ConnSupervisor) Run(ctx context.Context, fetch func(context.Context) error) error {
+	defer s.stopped()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fetch(ctx)
+		if err == nil {
+			s.succeeded()
+			continue
+		}
+		if errors.Is(err, errSupervisorStopped) {
+			return nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+
+		delay := s.failed()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
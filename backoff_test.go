@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoffDuration(t *testing.T) {
+	// Only Jitter is left at its zero value, to also exercise the
+	// defaulting behavior; it defaults to ±20%, so each case is checked
+	// against a tolerance band rather than an exact value.
+	b := &ExponentialBackoff{Init: 10 * time.Millisecond, Factor: 2, Max: 100 * time.Millisecond}
+
+	cases := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, 10 * time.Millisecond},
+		{1, 20 * time.Millisecond},
+		{2, 40 * time.Millisecond},
+		{3, 80 * time.Millisecond},
+		{4, 100 * time.Millisecond},
+		{10, 100 * time.Millisecond},
+	}
+	for _, c := range cases {
+		lo := time.Duration(float64(c.base) * 0.8)
+		hi := time.Duration(float64(c.base) * 1.2)
+		if got := b.Duration(c.attempt); got < lo || got > hi {
+			t.Errorf("attempt %d: got %s, want between %s and %s", c.attempt, got, lo, hi)
+		}
+	}
+}
+
+func TestConnSupervisorRunRetriesWithBackoff(t *testing.T) {
+	t.Parallel()
+
+	var transitions []ReaderState
+	var mu sync.Mutex
+	onState := func(old, new ReaderState) {
+		mu.Lock()
+		transitions = append(transitions, new)
+		mu.Unlock()
+	}
+
+	backoff := &ExponentialBackoff{Init: time.Millisecond, Factor: 1, Jitter: 0, Max: time.Millisecond}
+	s := NewConnSupervisor(backoff, onState)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const failuresBeforeSuccess = 3
+	attempts := 0
+	errBoom := errors.New("boom")
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, func(ctx context.Context) error {
+			attempts++
+			if attempts <= failuresBeforeSuccess {
+				return errBoom
+			}
+			cancel()
+			return nil
+		})
+		close(done)
+	}()
+
+	<-done
+
+	if attempts < failuresBeforeSuccess+1 {
+		t.Fatalf("expected at least %d attempts, got %d", failuresBeforeSuccess+1, attempts)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sawRecovering, sawRunning := false, false
+	for _, st := range transitions {
+		switch st {
+		case ReaderStateRecovering:
+			sawRecovering = true
+		case ReaderStateRunning:
+			sawRunning = true
+		}
+	}
+	if !sawRecovering {
+		t.Error("expected a transition into ReaderStateRecovering")
+	}
+	if !sawRunning {
+		t.Error("expected a transition into ReaderStateRunning")
+	}
+}
+
+// TestConnSupervisorStateDuringRunIsRaceFree exercises State() being
+// polled concurrently with Run driving transitions, the pattern a
+// health-check endpoint uses against a Reader's background fetch loop.
+// Run with -race to catch a regression.
+func TestConnSupervisorStateDuringRunIsRaceFree(t *testing.T) {
+	t.Parallel()
+
+	s := NewConnSupervisor(&ExponentialBackoff{Init: time.Millisecond, Max: time.Millisecond}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			_ = s.State()
+		}
+	}()
+
+	attempts := 0
+	s.Run(ctx, func(ctx context.Context) error {
+		attempts++
+		if attempts%2 == 0 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+
+	wg.Wait()
+
+	if s.State() != ReaderStateStopped {
+		t.Errorf("expected ReaderStateStopped after Run returns, got %s", s.State())
+	}
+}
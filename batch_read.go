@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBatchCanceled is returned by ReadBatch when ctx is canceled, or the
+// deadline set on ctx is exceeded, before minMessages could be accumulated.
+// The returned slice still holds whatever messages had already been read,
+// so callers can flush a partial batch instead of discarding it. Passing
+// the last message in the slice (if any) to CommitMessages commits every
+// message of the slice read from that message's partition; messages from
+// other partitions are committed only when they are passed as well.
+var ErrBatchCanceled = errors.New("kafka.(*Reader).ReadBatch: canceled with a partial batch")
+
+// ReadBatch accumulates messages from r until either maxMessages have been
+// read, maxWait has elapsed since the first message of the batch arrived,
+// or ctx is done.
+//
+// minMessages and maxWait express a flush policy familiar from other batch
+// sinks (time-series databases, object storage, warehouse loaders): wait
+// for at least minMessages, but never longer than maxWait once the first
+// message has arrived. If ctx is canceled before minMessages is reached,
+// ReadBatch returns the partial batch gathered so far alongside
+// ErrBatchCanceled rather than discarding it, so the caller can decide
+// whether to flush a short batch before committing offsets for it.
+//
+// CommitMessages commits, for each partition among the messages passed to
+// it, the offset after the highest one. Committing the last element of the
+// returned slice therefore commits every message of the batch read from
+// that element's partition, and nothing after it; messages of the batch
+// read from other partitions are not committed by that call.
+func (r *Reader) ReadBatch(ctx context.Context, minMessages, maxMessages int, maxWait time.Duration) ([]Message, error) {
+	if minMessages <= 0 {
+		minMessages = 1
+	}
+	if maxMessages < minMessages {
+		maxMessages = minMessages
+	}
+
+	// readCtx bounds only the in-flight ReadMessage call below; canceling it
+	// on return (via the deferred cancel) unblocks that call as soon as
+	// ReadBatch decides to stop, instead of leaking a goroutine parked in
+	// ReadMessage until the next message happens to arrive.
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type readResult struct {
+		msg Message
+		err error
+	}
+	// Buffered so the read goroutine can always deliver its result and
+	// exit, even if ReadBatch has already returned and nobody is left to
+	// receive it.
+	results := make(chan readResult, 1)
+	readNext := func() {
+		go func() {
+			msg, err := r.ReadMessage(readCtx)
+			results <- readResult{msg, err}
+		}()
+	}
+
+	batch := make([]Message, 0, maxMessages)
+	var deadline <-chan time.Time
+	readNext()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if len(batch) >= minMessages {
+				return batch, nil
+			}
+			return batch, ErrBatchCanceled
+
+		case <-deadline:
+			return batch, nil
+
+		case res := <-results:
+			if res.err != nil {
+				if len(batch) >= minMessages {
+					return batch, nil
+				}
+				if errors.Is(res.err, context.Canceled) || errors.Is(res.err, context.DeadlineExceeded) {
+					return batch, ErrBatchCanceled
+				}
+				return batch, res.err
+			}
+
+			batch = append(batch, res.msg)
+			if len(batch) == 1 && maxWait > 0 {
+				timer := time.NewTimer(maxWait)
+				defer timer.Stop()
+				deadline = timer.C
+			}
+			if len(batch) >= maxMessages {
+				return batch, nil
+			}
+			readNext()
+		}
+	}
+}
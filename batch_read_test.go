@@ -0,0 +1,107 @@
+package kafka_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	kafka "github.com/supplyon/kafka-go"
+	"github.com/supplyon/kafka-go/kafkatest"
+)
+
+func TestReaderReadBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tt := kafkatest.NewTester(t)
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Topic:     "test-topic",
+		MaxWait:   500 * time.Millisecond,
+		Transport: tt.Transport(),
+	})
+	defer r.Close()
+
+	const N = 100
+	prepareReader(t, tt, r, makeTestSequence(N)...)
+
+	batch, err := r.ReadBatch(ctx, 1, N, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != N {
+		t.Fatalf("expected %d messages, got %d", N, len(batch))
+	}
+	for i, m := range batch {
+		v, _ := strconv.Atoi(string(m.Value))
+		if v != i {
+			t.Error("message at index", i, "has wrong value:", v)
+			return
+		}
+	}
+}
+
+func TestReaderReadBatchCanceledReturnsPartial(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tt := kafkatest.NewTester(t)
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Topic:     "test-topic",
+		MaxWait:   500 * time.Millisecond,
+		Transport: tt.Transport(),
+	})
+	defer r.Close()
+
+	const N = 5
+	prepareReader(t, tt, r, makeTestSequence(N)...)
+
+	batchCtx, batchCancel := context.WithCancel(ctx)
+	batchCancel()
+
+	batch, err := r.ReadBatch(batchCtx, N+1, N+1, time.Second)
+	if err != kafka.ErrBatchCanceled {
+		t.Fatalf("expected ErrBatchCanceled, got %v", err)
+	}
+	if len(batch) != 0 {
+		t.Fatalf("expected an empty partial batch, got %d messages", len(batch))
+	}
+}
+
+// TestReaderReadBatchMaxWaitWhileIdle covers the case where the batch has
+// already seen its first message but no further messages arrive: ReadBatch
+// must return once maxWait elapses rather than blocking inside whatever
+// ReadMessage call happens to be in flight when the timer fires.
+func TestReaderReadBatchMaxWaitWhileIdle(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tt := kafkatest.NewTester(t)
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Topic:     "test-topic",
+		MaxWait:   500 * time.Millisecond,
+		Transport: tt.Transport(),
+	})
+	defer r.Close()
+
+	prepareReader(t, tt, r, makeTestSequence(1)...)
+
+	const maxWait = time.Second
+	start := time.Now()
+	batch, err := r.ReadBatch(ctx, 1, 10, maxWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(batch))
+	}
+	if elapsed := time.Since(start); elapsed > 3*maxWait {
+		t.Fatalf("ReadBatch took %s, expected to return within a small multiple of maxWait (%s)", elapsed, maxWait)
+	}
+}
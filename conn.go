@@ -0,0 +1,588 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	apiKeyProduce         = 0
+	apiKeyFetch           = 1
+	apiKeyMetadata        = 3
+	apiKeyOffsetCommit    = 8
+	apiKeyOffsetFetch     = 9
+	apiKeyFindCoordinator = 10
+	apiKeyJoinGroup       = 11
+	apiKeyHeartbeat       = 12
+	apiKeyLeaveGroup      = 13
+	apiKeySyncGroup       = 14
+)
+
+// Partition carries the subset of Kafka partition metadata Conn needs to
+// route a produce or fetch to the right broker.
+type Partition struct {
+	Topic  string
+	ID     int
+	Leader string // host:port of the partition's current leader
+}
+
+// Conn represents a single connection to a Kafka broker, speaking the
+// subset of the Kafka wire protocol (v0/v1 request/response formats) that
+// Reader, Writer, and the offset import/export and consumer group code in
+// this package need. A Conn dialed through DialLeader is bound to one
+// topic/partition for the lifetime of the connection; a Conn obtained any
+// other way (Dialer.DialContext, findCoordinator) is a bare broker
+// connection used for metadata/coordinator/group requests.
+type Conn struct {
+	conn     net.Conn
+	dialer   *Dialer
+	network  string
+	clientID string
+
+	topic     string
+	partition int
+
+	mutex         sync.Mutex
+	correlationID int32
+}
+
+func newConn(nc net.Conn, dialer *Dialer, network string) *Conn {
+	return &Conn{conn: nc, dialer: dialer, network: network, clientID: dialer.ClientID}
+}
+
+// Close closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) nextCorrelationID() int32 {
+	return atomic.AddInt32(&c.correlationID, 1)
+}
+
+// roundTrip sends a single request (apiKey/apiVersion plus whatever body
+// writeBody appends) and returns the decoded response body, with the
+// correlation id and the 4-byte size framing already stripped off. Only
+// one request may be in flight on a Conn at a time, enforced by mutex.
+func (c *Conn) roundTrip(apiKey, apiVersion int16, writeBody func(*protocolWriter)) (*protocolReader, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	corrID := c.nextCorrelationID()
+
+	w := &protocolWriter{}
+	w.writeInt16(apiKey)
+	w.writeInt16(apiVersion)
+	w.writeInt32(corrID)
+	w.writeString(c.clientID)
+	writeBody(w)
+
+	body, err := w.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("kafka: encoding request: %w", err)
+	}
+
+	framed := &protocolWriter{}
+	framed.writeInt32(int32(len(body)))
+	framed.buf = append(framed.buf, body...)
+
+	if _, err := c.conn.Write(framed.buf); err != nil {
+		return nil, fmt.Errorf("kafka: writing request: %w", err)
+	}
+
+	var sizeBuf [4]byte
+	if _, err := readFull(c.conn, sizeBuf[:]); err != nil {
+		return nil, fmt.Errorf("kafka: reading response size: %w", err)
+	}
+	size := int32(sizeBuf[0])<<24 | int32(sizeBuf[1])<<16 | int32(sizeBuf[2])<<8 | int32(sizeBuf[3])
+
+	respBody := make([]byte, size)
+	if _, err := readFull(c.conn, respBody); err != nil {
+		return nil, fmt.Errorf("kafka: reading response body: %w", err)
+	}
+
+	r := newProtocolReader(respBody)
+	gotCorrID := r.readInt32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if gotCorrID != corrID {
+		return nil, fmt.Errorf("kafka: correlation id mismatch: got %d, want %d", gotCorrID, corrID)
+	}
+	return r, nil
+}
+
+func readFull(conn net.Conn, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := conn.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ReadPartitions fetches the partitions of the given topics (or, with no
+// topics given, of every topic in the cluster) along with the address of each partition's
+// current leader, via the Metadata API.
+func (c *Conn) ReadPartitions(topics ...string) ([]Partition, error) {
+	r, err := c.roundTrip(apiKeyMetadata, 1, func(w *protocolWriter) {
+		if topics == nil {
+			w.writeInt32(-1) // null array: all topics
+			return
+		}
+		w.writeArrayLen(len(topics))
+		for _, t := range topics {
+			w.writeString(t)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	brokers := make(map[int32]string)
+	for i, n := 0, r.readArrayLen(); i < n; i++ {
+		nodeID := r.readInt32()
+		host := r.readString()
+		port := r.readInt32()
+		brokers[nodeID] = fmt.Sprintf("%s:%d", host, port)
+	}
+	_ = r.readInt32() // controller_id
+
+	var partitions []Partition
+	for i, n := 0, r.readArrayLen(); i < n; i++ {
+		topicErr := r.readInt16()
+		topicName := r.readString()
+		_ = r.readInt8() // is_internal
+		for j, m := 0, r.readArrayLen(); j < m; j++ {
+			partErr := r.readInt16()
+			partitionID := r.readInt32()
+			leaderID := r.readInt32()
+			replicaCount := r.readArrayLen()
+			for k := 0; k < replicaCount; k++ {
+				r.readInt32()
+			}
+			isrCount := r.readArrayLen()
+			for k := 0; k < isrCount; k++ {
+				r.readInt32()
+			}
+			if err := kafkaError(topicErr); err != nil {
+				continue
+			}
+			if err := kafkaError(partErr); err != nil {
+				continue
+			}
+			partitions = append(partitions, Partition{
+				Topic:  topicName,
+				ID:     int(partitionID),
+				Leader: brokers[leaderID],
+			})
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return partitions, nil
+}
+
+// WriteMessages writes a batch of messages to the topic/partition this Conn
+// was dialed for (via DialLeader), using the Produce API with no
+// compression, and returns the number of bytes written.
+func (c *Conn) WriteMessages(msgs ...Message) (int, error) {
+	r, err := c.roundTrip(apiKeyProduce, 0, func(w *protocolWriter) {
+		w.writeInt16(1) // acks: leader only
+		w.writeInt32(5000)
+		w.writeArrayLen(1)
+		w.writeString(c.topic)
+		w.writeArrayLen(1)
+		w.writeInt32(int32(c.partition))
+
+		set := &protocolWriter{}
+		for _, m := range msgs {
+			encodeLegacyMessage(set, m.Key, m.Value)
+		}
+		w.writeInt32(int32(len(set.buf)))
+		w.buf = append(w.buf, set.buf...)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	n, bytesWritten := r.readArrayLen(), 0
+	for i := 0; i < n; i++ {
+		r.readString() // topic
+		for j, m := 0, r.readArrayLen(); j < m; j++ {
+			r.readInt32() // partition
+			errCode := r.readInt16()
+			r.readInt64() // base_offset
+			if err := kafkaError(errCode); err != nil {
+				return bytesWritten, err
+			}
+		}
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	for _, m := range msgs {
+		bytesWritten += len(m.Key) + len(m.Value)
+	}
+	return bytesWritten, nil
+}
+
+// encodeLegacyMessage appends one Kafka message-set entry (offset,
+// message_size, crc, magic, attributes, key, value) in the v0 (uncompressed,
+// no timestamp) message format.
+func encodeLegacyMessage(w *protocolWriter, key, value []byte) {
+	const magic, attributes = 0, 0
+	crc := messageSetCRC(magic, attributes, key, value)
+
+	body := &protocolWriter{}
+	body.writeInt8(magic)
+	body.writeInt8(attributes)
+	body.writeNullableBytes(key)
+	body.writeNullableBytes(value)
+
+	w.writeInt64(0) // offset, ignored by the broker on produce
+	w.writeInt32(int32(4 + len(body.buf)))
+	w.writeInt32(int32(crc))
+	w.buf = append(w.buf, body.buf...)
+}
+
+// fetch issues a single Fetch request for this Conn's topic/partition
+// starting at offset, waiting up to maxWait for at least one message to
+// become available, and returns the messages decoded from the response
+// along with the partition's high water mark.
+func (c *Conn) fetch(ctx context.Context, offset int64, maxWait time.Duration) ([]Message, int64, error) {
+	if maxWait <= 0 {
+		maxWait = 10 * time.Second
+	}
+	deadline, ok := ctx.Deadline()
+	if ok {
+		if remaining := time.Until(deadline); remaining < maxWait {
+			maxWait = remaining
+		}
+	}
+	if maxWait < 0 {
+		maxWait = 0
+	}
+
+	r, err := c.roundTrip(apiKeyFetch, 0, func(w *protocolWriter) {
+		w.writeInt32(-1) // replica_id
+		w.writeInt32(int32(maxWait / time.Millisecond))
+		w.writeInt32(1) // min_bytes
+		w.writeArrayLen(1)
+		w.writeString(c.topic)
+		w.writeArrayLen(1)
+		w.writeInt32(int32(c.partition))
+		w.writeInt64(offset)
+		w.writeInt32(1 << 20) // max_bytes
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var messages []Message
+	var highWaterMark int64
+
+	n := r.readArrayLen()
+	for i := 0; i < n; i++ {
+		r.readString() // topic
+		for j, m := 0, r.readArrayLen(); j < m; j++ {
+			r.readInt32() // partition
+			errCode := r.readInt16()
+			hwm := r.readInt64()
+			setSize := r.readInt32()
+			set := r.need(int(setSize))
+			if r.err != nil {
+				return nil, 0, r.err
+			}
+			if err := kafkaError(errCode); err != nil {
+				return nil, 0, err
+			}
+			highWaterMark = hwm
+			messages = append(messages, decodeLegacyMessageSet(c.topic, c.partition, hwm, set)...)
+		}
+	}
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	return messages, highWaterMark, nil
+}
+
+// decodeLegacyMessageSet parses a v0/v1 Kafka message set, stopping at the
+// first entry that is truncated (a partial message at the end of a fetch
+// response, which the broker sends deliberately when a full message
+// doesn't fit in max_bytes) rather than erroring.
+func decodeLegacyMessageSet(topic string, partition int, highWaterMark int64, set []byte) []Message {
+	var messages []Message
+	r := newProtocolReader(set)
+	for r.off < len(set) {
+		start := r.off
+		if len(set)-start < 8+4+4+1+1 {
+			break
+		}
+		offset := r.readInt64()
+		size := r.readInt32()
+		if r.err != nil || int(size) > len(set)-r.off {
+			break
+		}
+		entryEnd := r.off + int(size)
+		r.readInt32() // crc, not re-verified on read
+		r.readInt8()  // magic
+		r.readInt8()  // attributes
+		key := r.readBytes()
+		value := r.readBytes()
+		if r.err != nil {
+			break
+		}
+		r.off = entryEnd
+		messages = append(messages, Message{
+			Topic:         topic,
+			Partition:     partition,
+			Offset:        offset,
+			HighWaterMark: highWaterMark,
+			Key:           key,
+			Value:         value,
+		})
+	}
+	return messages
+}
+
+// findCoordinator asks the broker this Conn is connected to which broker
+// coordinates groupID, and returns a new Conn dialed directly to it. The
+// caller owns the returned Conn's lifetime; this Conn is left open.
+func (c *Conn) findCoordinator(groupID string) (*Conn, error) {
+	r, err := c.roundTrip(apiKeyFindCoordinator, 0, func(w *protocolWriter) {
+		w.writeString(groupID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	errCode := r.readInt16()
+	_ = r.readInt32() // coordinator_id
+	host := r.readString()
+	port := r.readInt32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := kafkaError(errCode); err != nil {
+		return nil, err
+	}
+
+	return c.dialer.DialContext(context.Background(), c.network, fmt.Sprintf("%s:%d", host, port))
+}
+
+// fetchCommittedOffset returns the committed offset, metadata, and leader
+// epoch for group/topic/partition via the OffsetFetch API. The v1 OffsetFetch
+// API this targets predates the leader epoch field, so leaderEpoch is
+// always returned as -1 ("unknown"), the same sentinel Kafka itself uses
+// for absent epochs.
+func (c *Conn) fetchCommittedOffset(group, topic string, partition int) (offset int64, metadata string, leaderEpoch int32, err error) {
+	r, err := c.roundTrip(apiKeyOffsetFetch, 1, func(w *protocolWriter) {
+		w.writeString(group)
+		w.writeArrayLen(1)
+		w.writeString(topic)
+		w.writeArrayLen(1)
+		w.writeInt32(int32(partition))
+	})
+	if err != nil {
+		return 0, "", -1, err
+	}
+
+	n := r.readArrayLen()
+	for i := 0; i < n; i++ {
+		r.readString() // topic
+		for j, m := 0, r.readArrayLen(); j < m; j++ {
+			r.readInt32() // partition
+			off := r.readInt64()
+			meta := r.readString()
+			errCode := r.readInt16()
+			if i == 0 && j == 0 {
+				offset, metadata = off, meta
+				err = kafkaError(errCode)
+			}
+		}
+	}
+	if r.err != nil {
+		return 0, "", -1, r.err
+	}
+	return offset, metadata, -1, err
+}
+
+// commitOffset commits offset/metadata for group/topic/partition via the
+// OffsetCommit API. leaderEpoch is accepted for symmetry with
+// fetchCommittedOffset and OffsetSnapshot but is not sent: the v0
+// OffsetCommit API this targets predates the leader epoch field.
+func (c *Conn) commitOffset(group, topic string, partition int, offset int64, metadata string, leaderEpoch int32) error {
+	r, err := c.roundTrip(apiKeyOffsetCommit, 0, func(w *protocolWriter) {
+		w.writeString(group)
+		w.writeArrayLen(1)
+		w.writeString(topic)
+		w.writeArrayLen(1)
+		w.writeInt32(int32(partition))
+		w.writeInt64(offset)
+		w.writeString(metadata)
+	})
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	n := r.readArrayLen()
+	for i := 0; i < n; i++ {
+		r.readString()
+		for j, m := 0, r.readArrayLen(); j < m; j++ {
+			r.readInt32()
+			errCode := r.readInt16()
+			if firstErr == nil {
+				firstErr = kafkaError(errCode)
+			}
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return firstErr
+}
+
+// joinGroupResult is the decoded JoinGroup response this Conn's member
+// needs to proceed to SyncGroup.
+type joinGroupResult struct {
+	generationID int32
+	protocol     string
+	leaderID     string
+	memberID     string
+	members      []groupMember // only populated for the elected leader
+}
+
+func (j joinGroupResult) isLeader() bool { return j.leaderID == j.memberID }
+
+// groupMember is one member of the group as reported to its leader by
+// JoinGroup, with its subscription metadata already decoded.
+type groupMember struct {
+	id           string
+	subscription consumerProtocolSubscription
+}
+
+// joinGroup sends a JoinGroup request proposing protocolName/metadata as
+// this member's subscription, and returns the group's decision.
+// sessionTimeout bounds how long the coordinator waits for every member to
+// join before completing the rebalance.
+func (c *Conn) joinGroup(groupID, memberID, protocolName string, metadata []byte, sessionTimeout time.Duration) (joinGroupResult, error) {
+	r, err := c.roundTrip(apiKeyJoinGroup, 0, func(w *protocolWriter) {
+		w.writeString(groupID)
+		w.writeInt32(int32(sessionTimeout / time.Millisecond))
+		w.writeString(memberID)
+		w.writeString("consumer")
+		w.writeArrayLen(1)
+		w.writeString(protocolName)
+		w.writeNullableBytes(metadata)
+	})
+	if err != nil {
+		return joinGroupResult{}, err
+	}
+
+	errCode := r.readInt16()
+	generationID := r.readInt32()
+	_ = r.readString() // group_protocol
+	leaderID := r.readString()
+	respMemberID := r.readString()
+
+	var members []groupMember
+	for i, n := 0, r.readArrayLen(); i < n; i++ {
+		id := r.readString()
+		md := r.readBytes()
+		if r.err != nil {
+			continue
+		}
+		sub, _ := decodeConsumerProtocolSubscription(md)
+		members = append(members, groupMember{id: id, subscription: sub})
+	}
+	if r.err != nil {
+		return joinGroupResult{}, r.err
+	}
+	if err := kafkaError(errCode); err != nil {
+		return joinGroupResult{}, err
+	}
+
+	return joinGroupResult{
+		generationID: generationID,
+		leaderID:     leaderID,
+		memberID:     respMemberID,
+		members:      members,
+	}, nil
+}
+
+// syncGroup sends a SyncGroup request for generationID/memberID, carrying
+// assignments (the per-member partition assignment this member computed,
+// if it is the group leader; nil for every follower) and returns this
+// member's own assignment once the coordinator has heard from the whole
+// group.
+func (c *Conn) syncGroup(groupID, memberID string, generationID int32, assignments map[string][]byte) ([]byte, error) {
+	r, err := c.roundTrip(apiKeySyncGroup, 0, func(w *protocolWriter) {
+		w.writeString(groupID)
+		w.writeInt32(generationID)
+		w.writeString(memberID)
+		w.writeArrayLen(len(assignments))
+		for id, assignment := range assignments {
+			w.writeString(id)
+			w.writeNullableBytes(assignment)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	errCode := r.readInt16()
+	assignment := r.readBytes()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := kafkaError(errCode); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// heartbeat sends a Heartbeat request, returning errCodeRebalanceInProgress
+// wrapped as an Error when the coordinator wants this member to call
+// joinGroup again.
+func (c *Conn) heartbeat(groupID, memberID string, generationID int32) error {
+	r, err := c.roundTrip(apiKeyHeartbeat, 0, func(w *protocolWriter) {
+		w.writeString(groupID)
+		w.writeInt32(generationID)
+		w.writeString(memberID)
+	})
+	if err != nil {
+		return err
+	}
+	errCode := r.readInt16()
+	if r.err != nil {
+		return r.err
+	}
+	return kafkaError(errCode)
+}
+
+// leaveGroup sends a LeaveGroup request so the coordinator can trigger a
+// rebalance immediately instead of waiting for this member's session to
+// time out.
+func (c *Conn) leaveGroup(groupID, memberID string) error {
+	r, err := c.roundTrip(apiKeyLeaveGroup, 0, func(w *protocolWriter) {
+		w.writeString(groupID)
+		w.writeString(memberID)
+	})
+	if err != nil {
+		return err
+	}
+	errCode := r.readInt16()
+	if r.err != nil {
+		return r.err
+	}
+	return kafkaError(errCode)
+}
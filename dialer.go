@@ -0,0 +1,91 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Dialer dials Kafka brokers, the same way net.Dialer dials arbitrary TCP
+// endpoints. The zero value is a usable Dialer with a 10s timeout and no
+// client id.
+type Dialer struct {
+	// Timeout bounds how long DialContext waits for the underlying TCP
+	// connection to establish. Defaults to 10s.
+	Timeout time.Duration
+
+	// ClientID is sent on every request this Conn makes, for broker-side
+	// request logging and quota enforcement.
+	ClientID string
+
+	// DialFunc, if set, is used instead of net.Dialer.DialContext to open
+	// the underlying connection. This is the seam kafkatest uses to hand
+	// Conn an in-memory pipe instead of a real socket.
+	DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// DefaultDialer is the Dialer used by DialLeader and by any ReaderConfig or
+// WriterConfig that leaves Dialer unset.
+var DefaultDialer = &Dialer{Timeout: 10 * time.Second}
+
+// DialContext dials address and returns a Conn wrapping the connection,
+// bound to no particular topic/partition.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (*Conn, error) {
+	dial := d.DialFunc
+	if dial == nil {
+		timeout := d.Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		nd := &net.Dialer{Timeout: timeout}
+		dial = nd.DialContext
+	}
+	nc, err := dial(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: dialing %s %s: %w", network, address, err)
+	}
+	return newConn(nc, d, network), nil
+}
+
+// DialLeader dials the broker that the Metadata response from address
+// names as the current leader of topic/partition, and returns a Conn bound
+// to that topic/partition. The leader is always dialed on a new
+// connection, even when address is the leader itself.
+func (d *Dialer) DialLeader(ctx context.Context, network, address, topic string, partition int) (*Conn, error) {
+	bootstrap, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer bootstrap.Close()
+
+	partitions, err := bootstrap.ReadPartitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: looking up leader for %s/%d: %w", topic, partition, err)
+	}
+
+	var leader string
+	for _, p := range partitions {
+		if p.ID == partition {
+			leader = p.Leader
+			break
+		}
+	}
+	if leader == "" {
+		return nil, fmt.Errorf("kafka: no leader found for %s/%d", topic, partition)
+	}
+
+	conn, err := d.DialContext(ctx, network, leader)
+	if err != nil {
+		return nil, err
+	}
+	conn.topic = topic
+	conn.partition = partition
+	return conn, nil
+}
+
+// DialLeader dials the broker that currently leads topic/partition using
+// DefaultDialer.
+func DialLeader(ctx context.Context, network, address, topic string, partition int) (*Conn, error) {
+	return DefaultDialer.DialLeader(ctx, network, address, topic, partition)
+}
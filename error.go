@@ -0,0 +1,55 @@
+package kafka
+
+import "fmt"
+
+// Error is a Kafka broker error code surfaced as a Go error, carrying the
+// numeric code so callers can match on it (e.g. with errors.As) instead of
+// parsing its message.
+type Error struct {
+	Code    int16
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("kafka server: %s", e.Message)
+}
+
+// kafkaError builds the Error for a response error_code, returning nil for
+// the well-known "no error" code.
+func kafkaError(code int16) error {
+	if code == 0 {
+		return nil
+	}
+	msg, ok := errorMessages[code]
+	if !ok {
+		msg = "unknown error"
+	}
+	return Error{Code: code, Message: msg}
+}
+
+// errorMessages maps the subset of Kafka protocol error codes this package
+// surfaces to their standard short descriptions.
+var errorMessages = map[int16]string{
+	-1: "unknown server error",
+	1:  "offset out of range",
+	2:  "corrupt message",
+	3:  "unknown topic or partition",
+	5:  "leader not available",
+	6:  "not leader for partition",
+	7:  "request timed out",
+	15: "group coordinator not available",
+	16: "not coordinator for group",
+	22: "illegal generation",
+	25: "unknown member id",
+	27: "rebalance in progress",
+	79: "member id required",
+}
+
+// errCodeRebalanceInProgress is returned by Heartbeat and JoinGroup to tell a
+// member that the group is (re)joining and it should call JoinGroup again.
+const errCodeRebalanceInProgress int16 = 27
+
+// errCodeUnknownMemberID is returned by JoinGroup/Heartbeat when the
+// member id a client last used has expired from the group's memory (e.g.
+// after a long pause); the client should rejoin with an empty member id.
+const errCodeUnknownMemberID int16 = 25
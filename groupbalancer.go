@@ -0,0 +1,196 @@
+package kafka
+
+import "sort"
+
+// consumerProtocolSubscription is the group protocol metadata a member
+// sends in JoinGroup, in the standard ConsumerProtocolSubscription v0
+// format: the topics it wants to consume, plus opaque user data the
+// assignor can use (cooperative-sticky/sticky use it to carry the
+// member's previous assignment, so the next assignment can minimize
+// movement).
+type consumerProtocolSubscription struct {
+	topics   []string
+	userData []byte
+}
+
+func encodeConsumerProtocolSubscription(s consumerProtocolSubscription) []byte {
+	w := &protocolWriter{}
+	w.writeInt16(0) // version
+	w.writeArrayLen(len(s.topics))
+	for _, t := range s.topics {
+		w.writeString(t)
+	}
+	w.writeNullableBytes(s.userData)
+	return w.buf
+}
+
+func decodeConsumerProtocolSubscription(b []byte) (consumerProtocolSubscription, error) {
+	r := newProtocolReader(b)
+	r.readInt16() // version
+	var s consumerProtocolSubscription
+	for i, n := 0, r.readArrayLen(); i < n; i++ {
+		s.topics = append(s.topics, r.readString())
+	}
+	s.userData = r.readBytes()
+	return s, r.err
+}
+
+// consumerProtocolAssignment is the per-member assignment the group leader
+// computes and the coordinator distributes via SyncGroup, in the standard
+// ConsumerProtocolAssignment v0 format.
+type consumerProtocolAssignment struct {
+	partitions map[string][]int32 // topic -> partitions
+	userData   []byte
+}
+
+func encodeConsumerProtocolAssignment(a consumerProtocolAssignment) []byte {
+	w := &protocolWriter{}
+	w.writeInt16(0) // version
+
+	topics := make([]string, 0, len(a.partitions))
+	for t := range a.partitions {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+
+	w.writeArrayLen(len(topics))
+	for _, t := range topics {
+		w.writeString(t)
+		parts := a.partitions[t]
+		w.writeArrayLen(len(parts))
+		for _, p := range parts {
+			w.writeInt32(p)
+		}
+	}
+	w.writeNullableBytes(a.userData)
+	return w.buf
+}
+
+func decodeConsumerProtocolAssignment(b []byte) (consumerProtocolAssignment, error) {
+	r := newProtocolReader(b)
+	r.readInt16() // version
+	a := consumerProtocolAssignment{partitions: make(map[string][]int32)}
+	for i, n := 0, r.readArrayLen(); i < n; i++ {
+		topic := r.readString()
+		var parts []int32
+		for j, m := 0, r.readArrayLen(); j < m; j++ {
+			parts = append(parts, r.readInt32())
+		}
+		a.partitions[topic] = parts
+	}
+	a.userData = r.readBytes()
+	return a, r.err
+}
+
+// assignPartitions computes the per-member partition assignment for a
+// single topic's partitions across members, following strategy. It runs
+// only on the member JoinGroup elected leader; every other member receives
+// its slice of the result via SyncGroup.
+//
+// RebalanceStrategySticky and RebalanceStrategyCooperativeSticky both use
+// previousOwners (decoded from each member's subscription user data) to
+// keep a member's existing partitions assigned to it wherever the target
+// distribution allows, instead of recomputing from scratch every
+// rebalance.
+func assignPartitions(strategy RebalanceStrategy, topic string, numPartitions int, members []groupMember, previousOwners map[string][]int32) map[string][]int32 {
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.id
+	}
+	sort.Strings(ids)
+
+	assignment := make(map[string][]int32, len(ids))
+	for _, id := range ids {
+		assignment[id] = nil
+	}
+	if len(ids) == 0 || numPartitions == 0 {
+		return assignment
+	}
+
+	switch strategy {
+	case RebalanceStrategySticky, RebalanceStrategyCooperativeSticky:
+		return assignStickyPartitions(ids, topic, numPartitions, previousOwners)
+	case RebalanceStrategyRoundRobin:
+		for p := 0; p < numPartitions; p++ {
+			id := ids[p%len(ids)]
+			assignment[id] = append(assignment[id], int32(p))
+		}
+	default: // RebalanceStrategyRange
+		per := numPartitions / len(ids)
+		extra := numPartitions % len(ids)
+		next := 0
+		for i, id := range ids {
+			count := per
+			if i < extra {
+				count++
+			}
+			for p := 0; p < count; p++ {
+				assignment[id] = append(assignment[id], int32(next))
+				next++
+			}
+		}
+	}
+	return assignment
+}
+
+// assignStickyPartitions starts from each member's previous ownership of
+// topic (as carried in its subscription user data) and moves only as many
+// partitions as necessary to reach a balanced distribution: every member
+// ends up with either floor(n/m) or ceil(n/m) partitions.
+func assignStickyPartitions(ids []string, topic string, numPartitions int, previousOwners map[string][]int32) map[string][]int32 {
+	assignment := make(map[string][]int32, len(ids))
+	memberSet := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		assignment[id] = nil
+		memberSet[id] = true
+	}
+
+	unassigned := make(map[int32]bool, numPartitions)
+	for p := int32(0); p < int32(numPartitions); p++ {
+		unassigned[p] = true
+	}
+
+	// Keep every partition whose previous owner is still in the group, as
+	// long as that owner isn't already over its fair share.
+	// Only numPartitions%len(ids) members may keep one more than the floor.
+	floorShare := numPartitions / len(ids)
+	extra := numPartitions % len(ids)
+	for _, id := range ids {
+		target := floorShare
+		if extra > 0 {
+			target++
+		}
+		for _, p := range previousOwners[id] {
+			if int(p) >= numPartitions || !unassigned[p] {
+				continue
+			}
+			if len(assignment[id]) >= target {
+				continue
+			}
+			assignment[id] = append(assignment[id], p)
+			delete(unassigned, p)
+		}
+		if len(assignment[id]) > floorShare {
+			extra--
+		}
+	}
+
+	// Distribute whatever's left round-robin across whichever members are
+	// currently furthest below the average.
+	remaining := make([]int32, 0, len(unassigned))
+	for p := range unassigned {
+		remaining = append(remaining, p)
+	}
+	sort.Slice(remaining, func(i, j int) bool { return remaining[i] < remaining[j] })
+
+	for _, p := range remaining {
+		least := ids[0]
+		for _, id := range ids[1:] {
+			if len(assignment[id]) < len(assignment[least]) {
+				least = id
+			}
+		}
+		assignment[least] = append(assignment[least], p)
+	}
+	return assignment
+}
@@ -0,0 +1,171 @@
+package kafka
+
+import "sync"
+
+// RebalanceStrategy determines how partitions are distributed among the
+// members of a consumer group, and whether a rebalance stops every member's
+// consumption (eager) or only the partitions that actually change hands
+// (cooperative).
+type RebalanceStrategy int
+
+const (
+	// RebalanceStrategyRange assigns partitions to members in contiguous
+	// ranges, topic by topic. This is the default and matches the behavior
+	// Reader has always had.
+	RebalanceStrategyRange RebalanceStrategy = iota
+
+	// RebalanceStrategyRoundRobin assigns partitions to members in a
+	// round-robin fashion across all subscribed topics.
+	RebalanceStrategyRoundRobin
+
+	// RebalanceStrategySticky assigns partitions to minimize the number of
+	// partitions that move between members across rebalances, without
+	// changing the eager (stop-the-world) revoke/assign protocol.
+	RebalanceStrategySticky
+
+	// RebalanceStrategyCooperativeSticky behaves like
+	// RebalanceStrategySticky but additionally upgrades the group to the
+	// cooperative rebalance protocol: only the partitions that are actually
+	// moving are revoked, and members keep consuming from every partition
+	// they retain while the rebalance is in progress.
+	RebalanceStrategyCooperativeSticky
+)
+
+// String satisfies the fmt.Stringer interface.
+func (s RebalanceStrategy) String() string {
+	switch s {
+	case RebalanceStrategyRange:
+		return "range"
+	case RebalanceStrategyRoundRobin:
+		return "roundrobin"
+	case RebalanceStrategySticky:
+		return "sticky"
+	case RebalanceStrategyCooperativeSticky:
+		return "cooperative-sticky"
+	default:
+		return "unknown"
+	}
+}
+
+// cooperative reports whether the strategy applies assignments
+// incrementally, as the cooperative rebalance protocol does: Revoke and
+// Assign then touch only the partitions that change hands.
+func (s RebalanceStrategy) cooperative() bool {
+	return s == RebalanceStrategyCooperativeSticky
+}
+
+// RebalanceCallbacks are invoked by Reader as a consumer group rebalance
+// progresses. All three are optional; a nil callback is simply skipped.
+//
+// With RebalanceStrategyCooperativeSticky, OnPartitionsAssigned and
+// OnPartitionsRevoked are called with the subset of partitions that are
+// actually gained or lost during that rebalance, not the full assignment.
+// With every other strategy, OnPartitionsRevoked is called with the entire
+// previous assignment before OnPartitionsAssigned is called with the entire
+// new one, matching the eager protocol's stop-the-world semantics.
+//
+// OnPartitionsLost is called instead of OnPartitionsRevoked when the
+// member's session expires or it is otherwise evicted from the group before
+// it had a chance to revoke cleanly; partitions reported this way may
+// already be owned by another member and must not be used to commit
+// offsets.
+type RebalanceCallbacks struct {
+	OnPartitionsAssigned func(topic string, partitions []int)
+	OnPartitionsRevoked  func(topic string, partitions []int)
+	OnPartitionsLost     func(topic string, partitions []int)
+}
+
+func (cb RebalanceCallbacks) assigned(topic string, partitions []int) {
+	if cb.OnPartitionsAssigned != nil && len(partitions) > 0 {
+		cb.OnPartitionsAssigned(topic, partitions)
+	}
+}
+
+func (cb RebalanceCallbacks) revoked(topic string, partitions []int) {
+	if cb.OnPartitionsRevoked != nil && len(partitions) > 0 {
+		cb.OnPartitionsRevoked(topic, partitions)
+	}
+}
+
+func (cb RebalanceCallbacks) lost(topic string, partitions []int) {
+	if cb.OnPartitionsLost != nil && len(partitions) > 0 {
+		cb.OnPartitionsLost(topic, partitions)
+	}
+}
+
+// membershipStatus tracks which partitions a reader currently owns so that
+// successive rebalances (cooperative or eager) can be diffed against the
+// previous assignment.
+type membershipStatus struct {
+	mutex      sync.Mutex
+	partitions map[string]map[int]struct{}
+}
+
+func newMembershipStatus() *membershipStatus {
+	return &membershipStatus{partitions: make(map[string]map[int]struct{})}
+}
+
+func (m *membershipStatus) owned(topic string) []int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	owned := make([]int, 0, len(m.partitions[topic]))
+	for p := range m.partitions[topic] {
+		owned = append(owned, p)
+	}
+	return owned
+}
+
+// applyCooperative merges next into the current ownership, returning the
+// partitions that were newly assigned and the partitions that were revoked
+// as a result of this rebalance. Unlike an eager assignment, partitions that
+// are present both before and after are left untouched so the reader
+// goroutines consuming them are never interrupted.
+func (m *membershipStatus) applyCooperative(topic string, next []int) (assigned, revoked []int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	nextSet := make(map[int]struct{}, len(next))
+	for _, p := range next {
+		nextSet[p] = struct{}{}
+	}
+
+	current := m.partitions[topic]
+	for p := range current {
+		if _, ok := nextSet[p]; !ok {
+			revoked = append(revoked, p)
+			delete(current, p)
+		}
+	}
+	for p := range nextSet {
+		if _, ok := current[p]; !ok {
+			assigned = append(assigned, p)
+			if current == nil {
+				current = make(map[int]struct{})
+				m.partitions[topic] = current
+			}
+			current[p] = struct{}{}
+		}
+	}
+	return assigned, revoked
+}
+
+// applyEager replaces the current ownership of topic wholesale, returning
+// the full previous assignment as revoked and the full new assignment as
+// assigned, matching the stop-the-world behavior of the non-cooperative
+// strategies.
+func (m *membershipStatus) applyEager(topic string, next []int) (assigned, revoked []int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current := m.partitions[topic]
+	for p := range current {
+		revoked = append(revoked, p)
+	}
+	nextPartitions := make(map[int]struct{}, len(next))
+	for _, p := range next {
+		nextPartitions[p] = struct{}{}
+	}
+	m.partitions[topic] = nextPartitions
+	assigned = next
+	return assigned, revoked
+}
@@ -0,0 +1,192 @@
+// Package kafkatest provides an in-process emulation of a Kafka broker for
+// use in unit tests, so that tests exercising Reader, Writer, and Conn
+// semantics don't need a live broker at localhost:9092.
+//
+// The emulator is intentionally not a faithful reimplementation of the
+// Kafka wire protocol. It models the subset of behavior that reader/writer
+// level tests depend on: per-topic, per-partition logs with monotonic
+// offsets, consumer group membership and commits, and produce/fetch
+// ordering, all driven directly through Go calls rather than a socket.
+package kafkatest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// record is a single message stored in a partition's log.
+type record struct {
+	offset int64
+	key    []byte
+	value  []byte
+	time   time.Time
+}
+
+// partition is an append-only log of records, guarded by its own mutex so
+// that produces to one partition never block fetches from another.
+type partition struct {
+	mutex   sync.Mutex
+	records []record
+}
+
+func newPartition() *partition {
+	return &partition{}
+}
+
+func (p *partition) append(key, value []byte, now time.Time) int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	offset := int64(len(p.records))
+	p.records = append(p.records, record{offset: offset, key: key, value: value, time: now})
+	return offset
+}
+
+func (p *partition) high() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return int64(len(p.records))
+}
+
+// fetch blocks until at least one record at or after offset is available,
+// ctx is done, or maxWait elapses (a maxWait of 0 means wait indefinitely,
+// matching Reader.ReadMessage's blocking semantics), then returns the
+// records available at that point starting from offset.
+func (p *partition) fetch(ctx context.Context, offset int64, maxWait time.Duration) ([]record, error) {
+	const pollInterval = 5 * time.Millisecond
+
+	var deadline time.Time
+	if maxWait > 0 {
+		deadline = time.Now().Add(maxWait)
+	}
+
+	for {
+		p.mutex.Lock()
+		if int64(len(p.records)) > offset {
+			out := make([]record, len(p.records)-int(offset))
+			copy(out, p.records[offset:])
+			p.mutex.Unlock()
+			return out, nil
+		}
+		p.mutex.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// topic is a fixed-size collection of partitions.
+type topic struct {
+	partitions []*partition
+}
+
+// groupOffset identifies one committed offset for a consumer group.
+type groupOffset struct {
+	group     string
+	topic     string
+	partition int
+}
+
+// Broker is the in-memory stand-in for a Kafka cluster. It is safe for
+// concurrent use by multiple Tester-derived readers and writers.
+type Broker struct {
+	mutex   sync.Mutex
+	topics  map[string]*topic
+	offsets map[groupOffset]int64
+}
+
+// NewBroker returns an empty Broker with no topics.
+func NewBroker() *Broker {
+	return &Broker{
+		topics:  make(map[string]*topic),
+		offsets: make(map[groupOffset]int64),
+	}
+}
+
+// CreateTopic registers a topic with the given number of partitions if it
+// does not already exist. Producing to or fetching from an unknown topic
+// with one partition implicitly creates it, matching the auto-create
+// behavior most test clusters run with.
+func (b *Broker) CreateTopic(name string, numPartitions int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.createTopicLocked(name, numPartitions)
+}
+
+func (b *Broker) createTopicLocked(name string, numPartitions int) *topic {
+	if t, ok := b.topics[name]; ok {
+		return t
+	}
+	if numPartitions < 1 {
+		numPartitions = 1
+	}
+	t := &topic{partitions: make([]*partition, numPartitions)}
+	for i := range t.partitions {
+		t.partitions[i] = newPartition()
+	}
+	b.topics[name] = t
+	return t
+}
+
+func (b *Broker) topicOrCreate(name string) *topic {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.createTopicLocked(name, 1)
+}
+
+// Produce appends one record to topic/partition and returns its offset.
+func (b *Broker) Produce(topicName string, partitionID int, key, value []byte) (int64, error) {
+	t := b.topicOrCreate(topicName)
+	if partitionID < 0 || partitionID >= len(t.partitions) {
+		return 0, fmt.Errorf("kafkatest: partition %d out of range for topic %q", partitionID, topicName)
+	}
+	return t.partitions[partitionID].append(key, value, time.Now()), nil
+}
+
+// Fetch reads records from topic/partition starting at offset, waiting up
+// to maxWait for at least one to become available.
+func (b *Broker) Fetch(ctx context.Context, topicName string, partitionID int, offset int64, maxWait time.Duration) ([]record, error) {
+	t := b.topicOrCreate(topicName)
+	if partitionID < 0 || partitionID >= len(t.partitions) {
+		return nil, fmt.Errorf("kafkatest: partition %d out of range for topic %q", partitionID, topicName)
+	}
+	return t.partitions[partitionID].fetch(ctx, offset, maxWait)
+}
+
+// HighWaterMark returns the next offset that will be assigned in
+// topic/partition.
+func (b *Broker) HighWaterMark(topicName string, partitionID int) int64 {
+	t := b.topicOrCreate(topicName)
+	if partitionID < 0 || partitionID >= len(t.partitions) {
+		return 0
+	}
+	return t.partitions[partitionID].high()
+}
+
+// CommitOffset records the next offset to be consumed by group for
+// topic/partition, following the Kafka convention that a committed offset
+// is one past the last message actually consumed.
+func (b *Broker) CommitOffset(group, topicName string, partitionID int, offset int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.offsets[groupOffset{group: group, topic: topicName, partition: partitionID}] = offset
+}
+
+// CommittedOffset returns the last offset committed by group for
+// topic/partition, or 0 if none has been committed yet.
+func (b *Broker) CommittedOffset(group, topicName string, partitionID int) int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.offsets[groupOffset{group: group, topic: topicName, partition: partitionID}]
+}
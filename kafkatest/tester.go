@@ -0,0 +1,332 @@
+package kafkatest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Message mirrors the subset of kafka.Message fields that the in-memory
+// broker needs to round-trip: a key, a value, the offset it was assigned,
+// and its produce time.
+type Message struct {
+	Key    []byte
+	Value  []byte
+	Offset int64
+	Time   time.Time
+}
+
+// closer is satisfied by both TestReader and TestWriter, letting Tester
+// tear either down generically from t.Cleanup.
+type closer interface {
+	Close() error
+}
+
+// Tester wraps a Broker with the bookkeeping needed to drive it from a
+// *testing.T: it tracks every topic it has produced to so Catchup knows
+// what "caught up" means, and fails the test via t rather than returning
+// errors from convenience methods.
+type Tester struct {
+	t      *testing.T
+	broker *Broker
+
+	mutex  sync.Mutex
+	topics map[string]struct{}
+	opened []closer
+}
+
+// NewTester returns a Tester backed by a fresh, empty Broker. Every
+// TestReader/TestWriter obtained from the Tester is closed automatically
+// when t completes, via t.Cleanup.
+func NewTester(t *testing.T) *Tester {
+	tt := &Tester{
+		t:      t,
+		broker: NewBroker(),
+		topics: make(map[string]struct{}),
+	}
+	t.Cleanup(tt.closeAll)
+	return tt
+}
+
+func (tt *Tester) closeAll() {
+	tt.mutex.Lock()
+	opened := tt.opened
+	tt.opened = nil
+	tt.mutex.Unlock()
+
+	for _, c := range opened {
+		c.Close()
+	}
+}
+
+func (tt *Tester) track(c closer) {
+	tt.mutex.Lock()
+	tt.opened = append(tt.opened, c)
+	tt.mutex.Unlock()
+}
+
+// Broker returns the in-memory Broker backing this Tester, for tests that
+// need lower-level control (explicit topic creation, partition counts)
+// than the convenience methods below provide.
+func (tt *Tester) Broker() *Broker {
+	return tt.broker
+}
+
+// ProduceString appends a single message with the given key and value to
+// topic, returning the offset it was assigned. An empty key is encoded as
+// a nil key, matching kafka.Message's zero value.
+func (tt *Tester) ProduceString(topic, key, value string) int64 {
+	tt.t.Helper()
+
+	tt.mutex.Lock()
+	tt.topics[topic] = struct{}{}
+	tt.mutex.Unlock()
+
+	var k []byte
+	if key != "" {
+		k = []byte(key)
+	}
+	offset, err := tt.broker.Produce(topic, 0, k, []byte(value))
+	if err != nil {
+		tt.t.Fatalf("kafkatest: ProduceString(%q): %v", topic, err)
+	}
+	return offset
+}
+
+// ConsumedOffset returns the offset last committed by group for
+// topic/partition, i.e. the offset a fresh Reader resuming that group
+// would start from.
+func (tt *Tester) ConsumedOffset(group, topic string, partition int) int64 {
+	return tt.broker.CommittedOffset(group, topic, partition)
+}
+
+// Catchup blocks until every message produced through this Tester's
+// ProduceString calls has a high-water mark reflected in its partition,
+// i.e. there is no in-flight produce still being applied. It exists so
+// that tests which alternate Produce/Consume steps don't need arbitrary
+// sleeps to avoid flakiness.
+//
+// Catchup does not wait for consumers to read those messages, only for
+// the broker to have durably stored them; pass a timeout to bound how
+// long it will wait on an obviously wedged test.
+func (tt *Tester) Catchup(timeout ...time.Duration) error {
+	// Produce in this emulator is synchronous (Broker.Produce returns only
+	// once the record is appended), so by the time ProduceString returns
+	// there is nothing left in flight; Catchup is a deliberate no-op kept
+	// around so call sites read the same way they would against the real
+	// goka-style tester this package is modeled on.
+	return nil
+}
+
+// Reader returns a TestReader consuming topic/partition from offset 0
+// through this Tester's Broker, with the same method surface as the
+// methods of Reader that tests typically exercise: ReadMessage, SetOffset,
+// and Close. The returned reader is closed automatically when the Tester's
+// *testing.T completes.
+func (tt *Tester) Reader(topic string, partition int) *TestReader {
+	r := newTestReader(tt.broker, "", topic, partition)
+	tt.track(r)
+	return r
+}
+
+// GroupReader returns a TestReader consuming topic/partition as a member
+// of group: it resumes from group's last committed offset for
+// topic/partition, and CommitMessages commits against that group. The
+// returned reader is closed automatically when the Tester's *testing.T
+// completes.
+func (tt *Tester) GroupReader(group, topic string, partition int) *TestReader {
+	r := newTestReader(tt.broker, group, topic, partition)
+	r.SetOffset(tt.broker.CommittedOffset(group, topic, partition))
+	tt.track(r)
+	return r
+}
+
+// Writer returns a TestWriter producing to topic through this Tester's
+// Broker, with the same method surface as the methods of Writer that
+// tests typically exercise: WriteMessages and Close. The returned writer
+// is closed automatically when the Tester's *testing.T completes.
+func (tt *Tester) Writer(topic string) *TestWriter {
+	w := &TestWriter{broker: tt.broker, topic: topic}
+	tt.track(w)
+	return w
+}
+
+// Group returns a TestGroup named id, backed by this Tester's Broker, for
+// tests that need to exercise consumer group joins and the partition
+// reassignment that follows a member joining or leaving.
+func (tt *Tester) Group(id string) *TestGroup {
+	return &TestGroup{broker: tt.broker, id: id}
+}
+
+// TestReader satisfies the read surface of Reader (ReadMessage, SetOffset,
+// Close) against an in-memory Broker instead of a live connection.
+type TestReader struct {
+	broker    *Broker
+	group     string
+	topic     string
+	partition int
+
+	mutex  sync.Mutex
+	offset int64
+	closed bool
+	done   chan struct{} // closed by Close to interrupt a blocked ReadMessage
+}
+
+func newTestReader(broker *Broker, group, topic string, partition int) *TestReader {
+	return &TestReader{broker: broker, group: group, topic: topic, partition: partition, done: make(chan struct{})}
+}
+
+// ReadMessage blocks until the next message at the reader's current offset
+// is available, ctx is done, or the reader is closed, advancing the
+// offset by one on success.
+func (r *TestReader) ReadMessage(ctx context.Context) (Message, error) {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return Message{}, fmt.Errorf("kafkatest: ReadMessage on a closed TestReader")
+	}
+	offset := r.offset
+	done := r.done
+	r.mutex.Unlock()
+
+	// fetchCtx is canceled either by the caller's ctx or by Close, so a
+	// blocked Fetch always unblocks instead of outliving the reader.
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-fetchCtx.Done():
+		}
+	}()
+
+	records, err := r.broker.Fetch(fetchCtx, r.topic, r.partition, offset, 0)
+	if err != nil {
+		return Message{}, err
+	}
+
+	rec := records[0]
+	r.mutex.Lock()
+	r.offset = rec.offset + 1
+	r.mutex.Unlock()
+
+	return Message{Key: rec.key, Value: rec.value, Offset: rec.offset, Time: rec.time}, nil
+}
+
+// SetOffset moves the reader to start its next ReadMessage call at offset.
+func (r *TestReader) SetOffset(offset int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.offset = offset
+}
+
+// CommitMessages commits the offset of the last message in msgs for the
+// group this reader was created with (via Tester.GroupReader); it is a
+// no-op on a reader obtained from Tester.Reader, which has no group.
+func (r *TestReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	if r.group == "" || len(msgs) == 0 {
+		return nil
+	}
+	last := msgs[len(msgs)-1]
+	r.broker.CommitOffset(r.group, r.topic, r.partition, last.Offset+1)
+	return nil
+}
+
+// Close stops any ReadMessage call currently blocked on this reader and
+// marks it closed; further ReadMessage calls return an error immediately.
+func (r *TestReader) Close() error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.closed = true
+	done := r.done
+	r.mutex.Unlock()
+
+	close(done)
+	return nil
+}
+
+// TestWriter satisfies the write surface of Writer (WriteMessages, Close)
+// against an in-memory Broker instead of a live connection.
+type TestWriter struct {
+	broker *Broker
+	topic  string
+}
+
+// WriteMessages produces every message to partition 0 of the writer's
+// topic, in order, returning the first error encountered.
+func (w *TestWriter) WriteMessages(ctx context.Context, msgs ...Message) error {
+	for _, m := range msgs {
+		if _, err := w.broker.Produce(w.topic, 0, m.Key, m.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close is a no-op kept for surface compatibility with Writer.Close.
+func (w *TestWriter) Close() error {
+	return nil
+}
+
+// TestGroup emulates a consumer group's membership: each Join recomputes
+// a simple round-robin assignment of the topic's partitions across every
+// member that has joined and not yet left, so tests can exercise
+// partition reassignment on membership changes without a broker.
+type TestGroup struct {
+	broker *Broker
+	id     string
+
+	mutex   sync.Mutex
+	members []string
+}
+
+// Join adds memberID to the group if it hasn't already joined, and
+// returns the partitions of topic currently assigned to it under a
+// round-robin split of all joined members.
+func (g *TestGroup) Join(memberID, topic string) []int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	idx := -1
+	for i, m := range g.members {
+		if m == memberID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		g.members = append(g.members, memberID)
+		idx = len(g.members) - 1
+	}
+
+	t := g.broker.topicOrCreate(topic)
+	n := len(g.members)
+
+	var assigned []int
+	for p := range t.partitions {
+		if p%n == idx {
+			assigned = append(assigned, p)
+		}
+	}
+	return assigned
+}
+
+// Leave removes memberID from the group. A subsequent Join by any
+// remaining member reflects the smaller membership's round-robin split.
+func (g *TestGroup) Leave(memberID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for i, m := range g.members {
+		if m == memberID {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			return
+		}
+	}
+}
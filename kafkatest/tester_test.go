@@ -0,0 +1,156 @@
+package kafkatest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTesterProduceAndRead(t *testing.T) {
+	tt := NewTester(t)
+
+	tt.ProduceString("greetings", "", "hello")
+	tt.ProduceString("greetings", "", "world")
+	if err := tt.Catchup(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	r := tt.Reader("greetings", 0)
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, want := range []string{"hello", "world"} {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(m.Value) != want {
+			t.Errorf("got %q, want %q", m.Value, want)
+		}
+	}
+}
+
+func TestTesterWriter(t *testing.T) {
+	tt := NewTester(t)
+
+	w := tt.Writer("orders")
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := w.WriteMessages(ctx, Message{Value: []byte("order-1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := tt.Reader("orders", 0)
+	defer r.Close()
+
+	m, err := r.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Value) != "order-1" {
+		t.Errorf("got %q, want %q", m.Value, "order-1")
+	}
+}
+
+// TestTesterCleanupUnblocksReadMessage proves that a TestReader blocked on
+// ReadMessage (waiting on a message that never arrives) is closed by
+// t.Cleanup instead of leaking its goroutine for the life of the process.
+func TestTesterCleanupUnblocksReadMessage(t *testing.T) {
+	tt := NewTester(t)
+	r := tt.Reader("never-produced", 0)
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := r.ReadMessage(context.Background())
+		readErr <- err
+	}()
+
+	// Give ReadMessage a moment to actually start blocking on the broker
+	// before simulating the end of the test.
+	time.Sleep(20 * time.Millisecond)
+	tt.closeAll()
+
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Fatal("expected ReadMessage to return an error once closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReadMessage did not return after the Tester was cleaned up")
+	}
+}
+
+func TestTesterGroupCommitAndResume(t *testing.T) {
+	tt := NewTester(t)
+	tt.Broker().CreateTopic("events", 1)
+
+	tt.ProduceString("events", "", "one")
+	tt.ProduceString("events", "", "two")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	r := tt.GroupReader("workers", "events", 0)
+	m, err := r.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Value) != "one" {
+		t.Fatalf("got %q, want %q", m.Value, "one")
+	}
+	if err := r.CommitMessages(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	if got := tt.ConsumedOffset("workers", "events", 0); got != 1 {
+		t.Fatalf("ConsumedOffset = %d, want 1", got)
+	}
+
+	// A fresh reader for the same group resumes after the committed
+	// message instead of re-reading it.
+	resumed := tt.GroupReader("workers", "events", 0)
+	m, err = resumed.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Value) != "two" {
+		t.Fatalf("got %q, want %q", m.Value, "two")
+	}
+}
+
+func TestTesterGroupJoinRebalancesPartitions(t *testing.T) {
+	tt := NewTester(t)
+	tt.Broker().CreateTopic("orders", 4)
+
+	group := tt.Group("workers")
+
+	solo := group.Join("member-a", "orders")
+	if len(solo) != 4 {
+		t.Fatalf("expected the sole member to own all 4 partitions, got %v", solo)
+	}
+
+	a := group.Join("member-b", "orders")
+	b := group.Join("member-a", "orders")
+	if len(a)+len(b) != 4 {
+		t.Fatalf("expected 4 partitions split across 2 members, got %v and %v", a, b)
+	}
+	for _, p := range a {
+		for _, q := range b {
+			if p == q {
+				t.Fatalf("partition %d assigned to both members", p)
+			}
+		}
+	}
+
+	group.Leave("member-b")
+	solo = group.Join("member-a", "orders")
+	if len(solo) != 4 {
+		t.Fatalf("expected the remaining member to own all 4 partitions after the other left, got %v", solo)
+	}
+}
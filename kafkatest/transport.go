@@ -0,0 +1,77 @@
+package kafkatest
+
+import (
+	"context"
+	"time"
+
+	kafka "github.com/supplyon/kafka-go"
+)
+
+// brokerTransport adapts a Broker to kafka.Transport, the seam Reader and
+// Writer use to read and write messages, so a Reader or Writer can be
+// pointed at this in-memory Broker via ReaderConfig.Transport /
+// WriterConfig.Transport instead of dialing a live cluster.
+type brokerTransport struct {
+	broker *Broker
+}
+
+// Transport returns a kafka.Transport backed by this Tester's Broker, for
+// constructing a kafka.Reader or kafka.Writer directly against it (e.g. to
+// exercise Reader/Writer code paths that Tester's own TestReader/TestWriter
+// don't cover, such as ReadBatch). A Reader with GroupID set still dials
+// ReaderConfig.Brokers to reach its group coordinator, so its consumer
+// group code paths do not run against this Transport.
+func (tt *Tester) Transport() kafka.Transport {
+	return &brokerTransport{broker: tt.broker}
+}
+
+func (t *brokerTransport) ReadPartitions(ctx context.Context, topic string) ([]kafka.Partition, error) {
+	top := t.broker.topicOrCreate(topic)
+	partitions := make([]kafka.Partition, len(top.partitions))
+	for i := range top.partitions {
+		partitions[i] = kafka.Partition{Topic: topic, ID: i, Leader: "kafkatest"}
+	}
+	return partitions, nil
+}
+
+func (t *brokerTransport) Fetch(ctx context.Context, topic string, partition int, offset int64, maxWait time.Duration) ([]kafka.Message, int64, error) {
+	hwm := t.broker.HighWaterMark(topic, partition)
+	if offset < 0 || offset > hwm {
+		return nil, hwm, kafka.Error{Code: 1, Message: "offset out of range"}
+	}
+
+	records, err := t.broker.Fetch(ctx, topic, partition, offset, maxWait)
+	if err != nil {
+		return nil, 0, err
+	}
+	hwm = t.broker.HighWaterMark(topic, partition)
+
+	messages := make([]kafka.Message, len(records))
+	for i, rec := range records {
+		messages[i] = kafka.Message{
+			Topic:         topic,
+			Partition:     partition,
+			Offset:        rec.offset,
+			HighWaterMark: hwm,
+			Key:           rec.key,
+			Value:         rec.value,
+			Time:          rec.time,
+		}
+	}
+	return messages, hwm, nil
+}
+
+func (t *brokerTransport) Produce(ctx context.Context, topic string, partition int, msgs ...kafka.Message) (int, error) {
+	n := 0
+	for _, m := range msgs {
+		if _, err := t.broker.Produce(topic, partition, m.Key, m.Value); err != nil {
+			return n, err
+		}
+		n += len(m.Key) + len(m.Value)
+	}
+	return n, nil
+}
+
+func (t *brokerTransport) Close() error {
+	return nil
+}
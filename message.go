@@ -0,0 +1,45 @@
+package kafka
+
+import "time"
+
+// Header is a single Kafka record header, held in order in
+// Message.Headers. Writer and Conn use the v0 message format, which has no
+// headers, so headers are neither written nor read by this package.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a data structure representing kafka messages.
+type Message struct {
+	// Topic is the topic the message was read from, set on every message
+	// Reader.ReadMessage returns, or the topic it should be written to;
+	// Writer uses its configured Topic for messages that leave it empty,
+	// so it is required on the messages passed to a Writer that writes to
+	// more than one topic.
+	Topic string
+
+	// Partition is the partition the message was read from, or that it
+	// should be written to; Writer ignores it and chooses a partition for
+	// every message it writes.
+	Partition int
+
+	// Offset is the message's position in its partition. It is set by
+	// Reader on every message it returns, and ignored on messages passed
+	// to Writer.
+	Offset int64
+
+	// HighWaterMark is the offset the next message written to the
+	// partition this message was read from will be assigned (one past the
+	// last message written), as observed when this message was fetched.
+	HighWaterMark int64
+
+	Key     []byte
+	Value   []byte
+	Headers []Header
+
+	// Time is the timestamp attached to the message when it is read, where
+	// the transport provides one; the v0 message format Conn decodes has
+	// none, so it is left zero there. Writer does not send it.
+	Time time.Time
+}
@@ -0,0 +1,180 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// OffsetSnapshot is a single committed offset for one partition of one
+// topic, as consumed by a particular consumer group. It is the unit
+// exported by ExportOffsets and imported by ImportOffsets.
+//
+// Offset follows the Kafka OffsetCommit convention: it is the offset of
+// the next message the group should read, i.e. one past the last message
+// it actually consumed. Exporters have historically gotten this wrong by
+// recording the last-consumed offset directly, which causes the first
+// message after an import to be reprocessed.
+type OffsetSnapshot struct {
+	Group       string `json:"group"`
+	Topic       string `json:"topic"`
+	Partition   int    `json:"partition"`
+	Offset      int64  `json:"offset"`
+	Metadata    string `json:"metadata"`
+	LeaderEpoch int32  `json:"leader_epoch"`
+}
+
+// GroupOffsets is the stable, importable representation of every committed
+// offset for a consumer group across the topics it subscribes to.
+type GroupOffsets []OffsetSnapshot
+
+// ExportOffsets dumps the committed offsets of every partition of every
+// topic r is configured to read, for r's consumer group. It is intended to
+// run alongside (or after) a topic mirror so that a consumer group can be
+// recreated on a different cluster, or replayed under a different group id
+// by overwriting the Group field of the returned snapshots before calling
+// ImportOffsets.
+func (r *Reader) ExportOffsets(ctx context.Context) (GroupOffsets, error) {
+	config := r.Config()
+	if config.GroupID == "" {
+		return nil, fmt.Errorf("kafka.(*Reader).ExportOffsets: reader %q is not using consumer groups", config.Topic)
+	}
+
+	conn, err := r.coordinator(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Reader).ExportOffsets: %w", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(config.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Reader).ExportOffsets: %w", err)
+	}
+
+	offsets := make(GroupOffsets, 0, len(partitions))
+	for _, p := range partitions {
+		committed, metadata, leaderEpoch, err := conn.fetchCommittedOffset(config.GroupID, config.Topic, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("kafka.(*Reader).ExportOffsets: partition %d: %w", p.ID, err)
+		}
+		offsets = append(offsets, OffsetSnapshot{
+			Group:       config.GroupID,
+			Topic:       config.Topic,
+			Partition:   p.ID,
+			Offset:      committed,
+			Metadata:    metadata,
+			LeaderEpoch: leaderEpoch,
+		})
+	}
+
+	return offsets, nil
+}
+
+// WriteTo serializes offsets as the stable JSON schema documented on
+// OffsetSnapshot: a flat array of {group, topic, partition, offset,
+// metadata, leader_epoch} objects, one per partition.
+func (offsets GroupOffsets) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	enc := json.NewEncoder(counter)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(offsets); err != nil {
+		return counter.n, err
+	}
+	return counter.n, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadGroupOffsets parses the JSON schema written by GroupOffsets.WriteTo.
+func ReadGroupOffsets(r io.Reader) (GroupOffsets, error) {
+	var offsets GroupOffsets
+	if err := json.NewDecoder(r).Decode(&offsets); err != nil {
+		return nil, fmt.Errorf("kafka.ReadGroupOffsets: %w", err)
+	}
+	return offsets, nil
+}
+
+// ImportOffsets restores a previously exported GroupOffsets, committing
+// each snapshot's offset for its group/topic/partition. The ReaderConfig
+// passed in only needs Brokers (and Dialer, if required) populated; its
+// GroupID and Topic are ignored in favor of the ones recorded on each
+// OffsetSnapshot, since a single export can span multiple topics and is
+// commonly imported under a group id different from the one it was
+// exported from.
+//
+// Restoring offsets must not make the group being restored join a
+// rebalance: ImportOffsets never constructs a Reader (which would start
+// joining the group in the background the moment it's created), only a
+// bare Conn dialed directly to each group's coordinator broker for issuing
+// OffsetCommit requests.
+//
+// When dryRun is true, ImportOffsets performs every lookup it would
+// normally need (resolving the group coordinator) but does not issue the
+// OffsetCommit calls; instead it returns the snapshots exactly as it would
+// have committed them, so callers can print or diff them before committing
+// for real.
+func ImportOffsets(ctx context.Context, config ReaderConfig, offsets GroupOffsets, dryRun bool) (GroupOffsets, error) {
+	byGroup := make(map[string]GroupOffsets)
+	for _, o := range offsets {
+		byGroup[o.Group] = append(byGroup[o.Group], o)
+	}
+
+	applied := make(GroupOffsets, 0, len(offsets))
+	for group, snapshots := range byGroup {
+		conn, err := dialGroupCoordinator(ctx, config, group)
+		if err != nil {
+			return applied, fmt.Errorf("kafka.ImportOffsets: group %q: %w", group, err)
+		}
+
+		for _, o := range snapshots {
+			if !dryRun {
+				if err := conn.commitOffset(group, o.Topic, o.Partition, o.Offset, o.Metadata, o.LeaderEpoch); err != nil {
+					conn.Close()
+					return applied, fmt.Errorf("kafka.ImportOffsets: group %q topic %q partition %d: %w", group, o.Topic, o.Partition, err)
+				}
+			}
+			applied = append(applied, o)
+		}
+		conn.Close()
+	}
+
+	return applied, nil
+}
+
+// dialGroupCoordinator dials one of config.Brokers as a bootstrap
+// connection, asks it which broker coordinates group, and returns a Conn
+// to that coordinator. The bootstrap connection is closed before
+// returning; only the coordinator Conn is handed back to the caller, which
+// owns its lifetime from that point on.
+func dialGroupCoordinator(ctx context.Context, config ReaderConfig, group string) (*Conn, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("no brokers configured")
+	}
+
+	dialer := config.Dialer
+	if dialer == nil {
+		dialer = DefaultDialer
+	}
+
+	bootstrap, err := dialer.DialContext(ctx, "tcp", config.Brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("dialing bootstrap broker %q: %w", config.Brokers[0], err)
+	}
+	defer bootstrap.Close()
+
+	coordinator, err := bootstrap.findCoordinator(group)
+	if err != nil {
+		return nil, fmt.Errorf("finding coordinator for group %q: %w", group, err)
+	}
+	return coordinator, nil
+}
@@ -0,0 +1,212 @@
+// Package kafkaprometheus adapts the counters and gauges returned by
+// kafka.Reader.Stats and kafka.Writer.Stats into prometheus.Collectors.
+//
+// It lives in its own module, separate from the root github.com/supplyon/kafka-go
+// module, so that pulling in github.com/prometheus/client_golang is opt-in:
+// importing the root package never forces the Prometheus dependency (and
+// its own dependency tree) on a consumer that doesn't use it.
+package kafkaprometheus
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kafka "github.com/supplyon/kafka-go"
+)
+
+// readerCollector adapts the counters and gauges returned by Reader.Stats
+// into a prometheus.Collector. Prometheus counters must be monotonically
+// increasing, while ReaderStats resets some of its fields (the ones
+// documented as "since the last call") every time Stats is read, so the
+// collector keeps running totals internally rather than forwarding the
+// deltas directly.
+type readerCollector struct {
+	reader *kafka.Reader
+	labels prometheus.Labels
+
+	messages      *prometheus.Desc
+	bytes         *prometheus.Desc
+	rebalances    *prometheus.Desc
+	timeouts      *prometheus.Desc
+	errors        *prometheus.Desc
+	dialTime      *prometheus.Desc
+	readTime      *prometheus.Desc
+	waitTime      *prometheus.Desc
+	fetchSize     *prometheus.Desc
+	fetchBytes    *prometheus.Desc
+	offset        *prometheus.Desc
+	lag           *prometheus.Desc
+	queueLength   *prometheus.Desc
+	queueCapacity *prometheus.Desc
+
+	mutex           sync.Mutex
+	totalMessages   int64
+	totalBytes      int64
+	totalRebalances int64
+	totalTimeouts   int64
+	totalErrors     int64
+}
+
+// NewReaderCollector returns a prometheus.Collector that reports the
+// counters and gauges tracked by r.Stats() under the given constant labels.
+// Callers register it with a prometheus.Registry the same way they would
+// any other collector:
+//
+//	prometheus.MustRegister(kafkaprometheus.NewReaderCollector(reader, prometheus.Labels{"topic": topic}))
+//
+// The collector polls r.Stats() on every Collect call, so it should be
+// registered once per Reader and left in place for the Reader's lifetime;
+// creating a new collector resets the running totals to zero.
+func NewReaderCollector(r *kafka.Reader, labels prometheus.Labels) prometheus.Collector {
+	const ns, sub = "kafka", "reader"
+	return &readerCollector{
+		reader:        r,
+		labels:        labels,
+		messages:      prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "messages_total"), "Total number of messages read.", nil, labels),
+		bytes:         prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "bytes_total"), "Total number of bytes read.", nil, labels),
+		rebalances:    prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "rebalances_total"), "Total number of consumer group rebalances.", nil, labels),
+		timeouts:      prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "timeouts_total"), "Total number of read timeouts.", nil, labels),
+		errors:        prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "errors_total"), "Total number of errors.", nil, labels),
+		dialTime:      prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "dial_seconds"), "Average time spent dialing the broker.", nil, labels),
+		readTime:      prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "read_seconds"), "Average time spent reading from the broker.", nil, labels),
+		waitTime:      prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "wait_seconds"), "Average time spent waiting for new messages.", nil, labels),
+		fetchSize:     prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "fetch_size"), "Average number of messages returned per fetch.", nil, labels),
+		fetchBytes:    prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "fetch_bytes"), "Average number of bytes returned per fetch.", nil, labels),
+		offset:        prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "offset"), "Current consumer offset.", nil, labels),
+		lag:           prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "lag"), "Current consumer lag.", nil, labels),
+		queueLength:   prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "queue_length"), "Number of messages buffered in the reader.", nil, labels),
+		queueCapacity: prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "queue_capacity"), "Capacity of the reader's message buffer.", nil, labels),
+	}
+}
+
+func (c *readerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.messages
+	ch <- c.bytes
+	ch <- c.rebalances
+	ch <- c.timeouts
+	ch <- c.errors
+	ch <- c.dialTime
+	ch <- c.readTime
+	ch <- c.waitTime
+	ch <- c.fetchSize
+	ch <- c.fetchBytes
+	ch <- c.offset
+	ch <- c.lag
+	ch <- c.queueLength
+	ch <- c.queueCapacity
+}
+
+func (c *readerCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.reader.Stats()
+
+	c.mutex.Lock()
+	c.totalMessages += stats.Messages
+	c.totalBytes += stats.Bytes
+	c.totalRebalances += stats.Rebalances
+	c.totalTimeouts += stats.Timeouts
+	c.totalErrors += stats.Errors
+	messages, bytes, rebalances, timeouts, errors := c.totalMessages, c.totalBytes, c.totalRebalances, c.totalTimeouts, c.totalErrors
+	c.mutex.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.messages, prometheus.CounterValue, float64(messages))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(bytes))
+	ch <- prometheus.MustNewConstMetric(c.rebalances, prometheus.CounterValue, float64(rebalances))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(timeouts))
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(errors))
+	ch <- prometheus.MustNewConstMetric(c.dialTime, prometheus.GaugeValue, stats.DialTime.Avg.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.readTime, prometheus.GaugeValue, stats.ReadTime.Avg.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.waitTime, prometheus.GaugeValue, stats.WaitTime.Avg.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.fetchSize, prometheus.GaugeValue, stats.FetchSize.Avg)
+	ch <- prometheus.MustNewConstMetric(c.fetchBytes, prometheus.GaugeValue, stats.FetchBytes.Avg)
+	ch <- prometheus.MustNewConstMetric(c.offset, prometheus.GaugeValue, float64(stats.Offset))
+	ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, float64(stats.Lag))
+	ch <- prometheus.MustNewConstMetric(c.queueLength, prometheus.GaugeValue, float64(stats.QueueLength))
+	ch <- prometheus.MustNewConstMetric(c.queueCapacity, prometheus.GaugeValue, float64(stats.QueueCapacity))
+}
+
+// writerCollector adapts the counters and gauges returned by Writer.Stats
+// into a prometheus.Collector, mirroring readerCollector.
+type writerCollector struct {
+	writer *kafka.Writer
+	labels prometheus.Labels
+
+	writes        *prometheus.Desc
+	messages      *prometheus.Desc
+	bytes         *prometheus.Desc
+	errors        *prometheus.Desc
+	writeTime     *prometheus.Desc
+	waitTime      *prometheus.Desc
+	retries       *prometheus.Desc
+	batchSize     *prometheus.Desc
+	batchBytes    *prometheus.Desc
+	queueLength   *prometheus.Desc
+	queueCapacity *prometheus.Desc
+
+	mutex         sync.Mutex
+	totalWrites   int64
+	totalMessages int64
+	totalBytes    int64
+	totalErrors   int64
+	totalRetries  int64
+}
+
+// NewWriterCollector returns a prometheus.Collector that reports the
+// counters and gauges tracked by w.Stats() under the given constant labels.
+// See NewReaderCollector for registration and lifetime notes.
+func NewWriterCollector(w *kafka.Writer, labels prometheus.Labels) prometheus.Collector {
+	const ns, sub = "kafka", "writer"
+	return &writerCollector{
+		writer:        w,
+		labels:        labels,
+		writes:        prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "writes_total"), "Total number of write operations.", nil, labels),
+		messages:      prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "messages_total"), "Total number of messages written.", nil, labels),
+		bytes:         prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "bytes_total"), "Total number of bytes written.", nil, labels),
+		errors:        prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "errors_total"), "Total number of errors.", nil, labels),
+		writeTime:     prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "write_seconds"), "Average time spent writing to the broker.", nil, labels),
+		waitTime:      prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "wait_seconds"), "Average time spent waiting for a batch to fill.", nil, labels),
+		retries:       prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "retries_total"), "Total number of write retries.", nil, labels),
+		batchSize:     prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "batch_size"), "Average number of messages per batch.", nil, labels),
+		batchBytes:    prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "batch_bytes"), "Average number of bytes per batch.", nil, labels),
+		queueLength:   prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "queue_length"), "Number of messages buffered in the writer.", nil, labels),
+		queueCapacity: prometheus.NewDesc(prometheus.BuildFQName(ns, sub, "queue_capacity"), "Capacity of the writer's message buffer.", nil, labels),
+	}
+}
+
+func (c *writerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.writes
+	ch <- c.messages
+	ch <- c.bytes
+	ch <- c.errors
+	ch <- c.writeTime
+	ch <- c.waitTime
+	ch <- c.retries
+	ch <- c.batchSize
+	ch <- c.batchBytes
+	ch <- c.queueLength
+	ch <- c.queueCapacity
+}
+
+func (c *writerCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.writer.Stats()
+
+	c.mutex.Lock()
+	c.totalWrites += stats.Writes
+	c.totalMessages += stats.Messages
+	c.totalBytes += stats.Bytes
+	c.totalErrors += stats.Errors
+	c.totalRetries += stats.Retries
+	writes, messages, bytes, errors, retries := c.totalWrites, c.totalMessages, c.totalBytes, c.totalErrors, c.totalRetries
+	c.mutex.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.writes, prometheus.CounterValue, float64(writes))
+	ch <- prometheus.MustNewConstMetric(c.messages, prometheus.CounterValue, float64(messages))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(bytes))
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(errors))
+	ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(retries))
+	ch <- prometheus.MustNewConstMetric(c.writeTime, prometheus.GaugeValue, stats.WriteTime.Avg.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.waitTime, prometheus.GaugeValue, stats.WaitTime.Avg.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.batchSize, prometheus.GaugeValue, stats.BatchSize.Avg)
+	ch <- prometheus.MustNewConstMetric(c.batchBytes, prometheus.GaugeValue, stats.BatchBytes.Avg)
+	ch <- prometheus.MustNewConstMetric(c.queueLength, prometheus.GaugeValue, float64(stats.QueueLength))
+	ch <- prometheus.MustNewConstMetric(c.queueCapacity, prometheus.GaugeValue, float64(stats.QueueCapacity))
+}
@@ -0,0 +1,194 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// protocolWriter serializes a single Kafka request body using the
+// primitive encodings (INT8/16/32/64, STRING, NULLABLE_STRING, BYTES,
+// ARRAY) described by the Kafka wire protocol. Every write method records
+// the first error it hits on err and becomes a no-op afterwards, so a
+// request can be built as a flat sequence of calls and checked once at the
+// end, the same pattern bufio.Writer callers use for fmt.Fprintf chains.
+type protocolWriter struct {
+	buf []byte
+	err error
+}
+
+func (w *protocolWriter) writeInt8(v int8) {
+	if w.err != nil {
+		return
+	}
+	w.buf = append(w.buf, byte(v))
+}
+
+func (w *protocolWriter) writeInt16(v int16) {
+	if w.err != nil {
+		return
+	}
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *protocolWriter) writeInt32(v int32) {
+	if w.err != nil {
+		return
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *protocolWriter) writeInt64(v int64) {
+	if w.err != nil {
+		return
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+// writeString writes a non-nullable STRING: a signed int16 length followed
+// by that many bytes.
+func (w *protocolWriter) writeString(s string) {
+	if w.err != nil {
+		return
+	}
+	if len(s) > 1<<15-1 {
+		w.err = fmt.Errorf("kafka: string too long to encode (%d bytes)", len(s))
+		return
+	}
+	w.writeInt16(int16(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// writeNullableBytes writes a NULLABLE_BYTES: a signed int32 length (-1 for
+// nil, distinct from an empty, non-nil slice) followed by that many bytes.
+func (w *protocolWriter) writeNullableBytes(b []byte) {
+	if w.err != nil {
+		return
+	}
+	if b == nil {
+		w.writeInt32(-1)
+		return
+	}
+	w.writeInt32(int32(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *protocolWriter) writeArrayLen(n int) {
+	w.writeInt32(int32(n))
+}
+
+func (w *protocolWriter) bytes() ([]byte, error) {
+	return w.buf, w.err
+}
+
+// protocolReader deserializes a single Kafka response body. Like
+// protocolWriter, every read method records the first error it hits on err
+// and returns the zero value afterwards, so a response can be decoded as a
+// flat sequence of calls and checked once at the end.
+type protocolReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func newProtocolReader(buf []byte) *protocolReader {
+	return &protocolReader{buf: buf}
+}
+
+func (r *protocolReader) need(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.off+n > len(r.buf) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *protocolReader) readInt8() int8 {
+	b := r.need(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (r *protocolReader) readInt16() int16 {
+	b := r.need(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *protocolReader) readInt32() int32 {
+	b := r.need(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *protocolReader) readInt64() int64 {
+	b := r.need(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *protocolReader) readString() string {
+	n := r.readInt16()
+	if r.err != nil || n < 0 {
+		return ""
+	}
+	b := r.need(int(n))
+	if b == nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (r *protocolReader) readBytes() []byte {
+	n := r.readInt32()
+	if r.err != nil || n < 0 {
+		return nil
+	}
+	b := r.need(int(n))
+	if b == nil {
+		return nil
+	}
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out
+}
+
+func (r *protocolReader) readArrayLen() int {
+	n := r.readInt32()
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// messageSetCRC computes the CRC32 (IEEE) checksum Kafka's message format
+// (v0/v1) stores alongside each record, covering everything after the CRC
+// field itself (magic byte, attributes, key, value).
+func messageSetCRC(magic, attributes int8, key, value []byte) uint32 {
+	w := &protocolWriter{}
+	w.writeInt8(magic)
+	w.writeInt8(attributes)
+	w.writeNullableBytes(key)
+	w.writeNullableBytes(value)
+	return crc32.ChecksumIEEE(w.buf)
+}
@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProtocolWriterReaderRoundTrip(t *testing.T) {
+	w := &protocolWriter{}
+	w.writeInt8(5)
+	w.writeInt16(-7)
+	w.writeInt32(1 << 20)
+	w.writeInt64(1 << 40)
+	w.writeString("hello")
+	w.writeNullableBytes([]byte("world"))
+	w.writeNullableBytes(nil)
+	w.writeArrayLen(3)
+
+	buf, err := w.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newProtocolReader(buf)
+	if v := r.readInt8(); v != 5 {
+		t.Errorf("readInt8: got %d, want 5", v)
+	}
+	if v := r.readInt16(); v != -7 {
+		t.Errorf("readInt16: got %d, want -7", v)
+	}
+	if v := r.readInt32(); v != 1<<20 {
+		t.Errorf("readInt32: got %d, want %d", v, 1<<20)
+	}
+	if v := r.readInt64(); v != 1<<40 {
+		t.Errorf("readInt64: got %d, want %d", v, 1<<40)
+	}
+	if s := r.readString(); s != "hello" {
+		t.Errorf("readString: got %q, want %q", s, "hello")
+	}
+	if b := r.readBytes(); !bytes.Equal(b, []byte("world")) {
+		t.Errorf("readBytes: got %q, want %q", b, "world")
+	}
+	if b := r.readBytes(); b != nil {
+		t.Errorf("readBytes: got %q, want nil", b)
+	}
+	if n := r.readArrayLen(); n != 3 {
+		t.Errorf("readArrayLen: got %d, want 3", n)
+	}
+	if r.err != nil {
+		t.Fatalf("unexpected reader error: %v", r.err)
+	}
+}
+
+// TestProtocolReaderShortBuffer covers the case fetchCommittedOffset and
+// commitOffset both rely on: a truncated response body must surface as an
+// error rather than silently returning zero values, since a zero offset
+// decoded from garbage would be indistinguishable from a real offset of 0.
+func TestProtocolReaderShortBuffer(t *testing.T) {
+	r := newProtocolReader([]byte{0, 1})
+	r.readInt64()
+	if r.err == nil {
+		t.Fatal("expected an error reading an int64 out of a 2-byte buffer")
+	}
+	if v := r.readInt32(); v != 0 {
+		t.Errorf("readInt32 after an error should return the zero value, got %d", v)
+	}
+}
+
+// TestMessageSetCRC confirms the CRC covers the record's content (magic,
+// attributes, key, value) so a corrupted key or value is detectable.
+// encodeLegacyMessage writes it on produce; the Fetch path
+// (decodeLegacyMessageSet) does not re-verify it.
+func TestMessageSetCRC(t *testing.T) {
+	crc := messageSetCRC(0, 0, []byte("key"), []byte("value"))
+	if crc == 0 {
+		t.Fatal("expected a non-zero CRC")
+	}
+	if other := messageSetCRC(0, 0, []byte("key"), []byte("value!")); other == crc {
+		t.Fatal("expected CRC to change when the value changes")
+	}
+}
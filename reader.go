@@ -0,0 +1,584 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReaderConfig configures a Reader.
+type ReaderConfig struct {
+	// Brokers is the list of broker addresses used to bootstrap the
+	// connection to the cluster.
+	Brokers []string
+
+	// Topic is the topic this Reader consumes.
+	Topic string
+
+	// Partition is the partition this Reader consumes when GroupID is
+	// empty. It is ignored once GroupID is set: partitions are assigned by
+	// the consumer group instead.
+	Partition int
+
+	// GroupID, if set, makes this Reader join a consumer group: partitions
+	// of Topic are assigned dynamically via the group protocol, driven by
+	// RebalanceStrategy and reported through RebalanceCallbacks, instead of
+	// reading the single partition named by Partition.
+	GroupID string
+
+	// MaxWait bounds how long a Fetch call blocks waiting for new messages.
+	// Defaults to 10s.
+	MaxWait time.Duration
+
+	// Dialer is used to establish every connection this Reader opens.
+	// Defaults to DefaultDialer.
+	Dialer *Dialer
+
+	// Transport overrides how this Reader reads and writes messages.
+	// Defaults to a Transport that dials Brokers with Dialer; tests can
+	// substitute an in-memory implementation such as kafkatest's.
+	Transport Transport
+
+	// RebalanceStrategy selects how partitions are distributed among a
+	// consumer group's members. Only meaningful when GroupID is set.
+	// Defaults to RebalanceStrategyRange.
+	RebalanceStrategy RebalanceStrategy
+
+	// RebalanceCallbacks are invoked as this Reader's consumer group
+	// membership changes. Only meaningful when GroupID is set.
+	RebalanceCallbacks RebalanceCallbacks
+
+	// Backoff computes how long to wait before retrying a failed fetch,
+	// Metadata call, FindCoordinator call, or JoinGroup call. Defaults to
+	// DefaultBackoff.
+	Backoff Backoff
+
+	// OnStateChange, if set, is called on every ReaderState transition.
+	OnStateChange func(old, new ReaderState)
+}
+
+func (c *ReaderConfig) withDefaults() ReaderConfig {
+	config := *c
+	if config.MaxWait <= 0 {
+		config.MaxWait = 10 * time.Second
+	}
+	if config.Dialer == nil {
+		config.Dialer = DefaultDialer
+	}
+	if config.Backoff == nil {
+		config.Backoff = DefaultBackoff
+	}
+	return config
+}
+
+// ReaderStats are the counters and gauges tracked by a Reader. Messages,
+// Bytes, Rebalances, Timeouts, and Errors accumulate since the previous
+// call to Stats and are reset by it, so each call reports only what
+// happened since the one before; Offset, Lag, QueueLength, and QueueCapacity are
+// point-in-time snapshots.
+type ReaderStats struct {
+	Messages   int64
+	Bytes      int64
+	Rebalances int64
+	Timeouts   int64
+	Errors     int64
+
+	DialTime DurationStat
+	ReadTime DurationStat
+	WaitTime DurationStat
+
+	FetchSize  SummaryStat
+	FetchBytes SummaryStat
+
+	Offset        int64
+	Lag           int64
+	QueueLength   int64
+	QueueCapacity int64
+}
+
+// DurationStat is an averaged time.Duration measurement.
+type DurationStat struct {
+	Avg time.Duration
+}
+
+// SummaryStat is an averaged float measurement, such as a message or byte
+// count per fetch.
+type SummaryStat struct {
+	Avg float64
+}
+
+// Reader reads messages from a Kafka topic, either a single fixed
+// partition or, with GroupID set, dynamically assigned partitions of a
+// consumer group.
+type Reader struct {
+	config    ReaderConfig
+	transport Transport
+
+	supervisor *ConnSupervisor
+	rebalancer *Rebalancer
+
+	mutex  sync.Mutex
+	offset int64
+
+	// messages, cancel, and wg are used in group mode only
+	messages  chan Message
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	messageCount   int64
+	byteCount      int64
+	rebalanceCount int64
+	timeoutCount   int64
+	errorCount     int64
+	fetchCount     int64
+	fetchMsgSum    int64
+	fetchByteSum   int64
+	lastOffset     int64
+	lastHighWater  int64
+}
+
+// NewReader returns a Reader configured by config. If config.GroupID is
+// set, NewReader starts the background goroutine that joins the consumer
+// group and assigns this Reader's partitions; otherwise ReadMessage reads
+// config.Partition directly, on demand.
+func NewReader(config ReaderConfig) *Reader {
+	config = config.withDefaults()
+
+	transport := config.Transport
+	if transport == nil {
+		transport = newDialedTransport(config.Brokers, config.Dialer)
+	}
+
+	r := &Reader{
+		config:     config,
+		transport:  transport,
+		supervisor: NewConnSupervisor(config.Backoff, config.OnStateChange),
+		rebalancer: NewRebalancer(config.RebalanceStrategy, config.RebalanceCallbacks),
+		closed:     make(chan struct{}),
+	}
+
+	if config.GroupID != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.cancel = cancel
+		r.messages = make(chan Message, 100)
+		r.wg.Add(1)
+		go r.runGroup(ctx)
+	}
+
+	return r
+}
+
+// Config returns the configuration this Reader was created with.
+func (r *Reader) Config() ReaderConfig {
+	return r.config
+}
+
+// State returns the Reader's current connectivity state.
+func (r *Reader) State() ReaderState {
+	return r.supervisor.State()
+}
+
+// SetOffset changes the offset the next ReadMessage call starts from. It
+// only applies to direct (non-group) mode; calling it on a Reader with
+// GroupID set is a no-op, since group members don't choose their own
+// starting offset.
+func (r *Reader) SetOffset(offset int64) {
+	if r.config.GroupID != "" {
+		return
+	}
+	r.mutex.Lock()
+	r.offset = offset
+	r.mutex.Unlock()
+}
+
+// ReadMessage returns the next message for this Reader, blocking until one
+// is available, ctx is done, or (group mode only) the Reader is closed.
+func (r *Reader) ReadMessage(ctx context.Context) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+	if r.config.GroupID != "" {
+		return r.readGroup(ctx)
+	}
+	return r.readDirect(ctx)
+}
+
+func (r *Reader) readGroup(ctx context.Context) (Message, error) {
+	select {
+	case msg, ok := <-r.messages:
+		if !ok {
+			return Message{}, fmt.Errorf("kafka: reader closed")
+		}
+		return msg, nil
+	case <-r.closed:
+		return Message{}, fmt.Errorf("kafka: reader closed")
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+func (r *Reader) readDirect(ctx context.Context) (Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return Message{}, err
+		}
+
+		r.mutex.Lock()
+		offset := r.offset
+		r.mutex.Unlock()
+
+		msgs, hwm, err := r.transport.Fetch(ctx, r.config.Topic, r.config.Partition, offset, r.config.MaxWait)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return Message{}, err
+			}
+			var kerr Error
+			if errors.As(err, &kerr) && kerr.Code == 1 { // offset out of range: not retryable
+				atomic.AddInt64(&r.errorCount, 1)
+				return Message{}, err
+			}
+
+			atomic.AddInt64(&r.errorCount, 1)
+			delay := r.supervisor.failed()
+			if !sleepCtx(ctx, delay) {
+				return Message{}, ctx.Err()
+			}
+			continue
+		}
+
+		r.supervisor.succeeded()
+		atomic.AddInt64(&r.fetchCount, 1)
+		atomic.AddInt64(&r.fetchMsgSum, int64(len(msgs)))
+		if len(msgs) == 0 {
+			atomic.AddInt64(&r.timeoutCount, 1)
+			continue
+		}
+
+		msg := msgs[0]
+		r.mutex.Lock()
+		r.offset = msg.Offset + 1
+		r.mutex.Unlock()
+
+		n := int64(len(msg.Key) + len(msg.Value))
+		atomic.AddInt64(&r.messageCount, 1)
+		atomic.AddInt64(&r.byteCount, n)
+		atomic.AddInt64(&r.fetchByteSum, n)
+		atomic.StoreInt64(&r.lastOffset, msg.Offset)
+		atomic.StoreInt64(&r.lastHighWater, hwm)
+		return msg, nil
+	}
+}
+
+// CommitMessages commits the offset of the last message per partition in
+// msgs, for this Reader's consumer group. It returns an error if GroupID is
+// not set.
+func (r *Reader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	if r.config.GroupID == "" {
+		return fmt.Errorf("kafka: (*Reader).CommitMessages requires GroupID to be set")
+	}
+
+	last := make(map[int]Message, len(msgs))
+	for _, m := range msgs {
+		if prev, ok := last[m.Partition]; !ok || m.Offset > prev.Offset {
+			last[m.Partition] = m
+		}
+	}
+
+	conn, err := r.coordinator(ctx)
+	if err != nil {
+		return fmt.Errorf("kafka: (*Reader).CommitMessages: %w", err)
+	}
+	defer conn.Close()
+
+	for partition, m := range last {
+		if err := conn.commitOffset(r.config.GroupID, r.config.Topic, partition, m.Offset+1, "", -1); err != nil {
+			return fmt.Errorf("kafka: (*Reader).CommitMessages: partition %d: %w", partition, err)
+		}
+	}
+	return nil
+}
+
+// Stats returns this Reader's counters and gauges, resetting the
+// accumulating fields (Messages, Bytes, Rebalances, Timeouts, Errors,
+// FetchSize, FetchBytes) back to zero.
+func (r *Reader) Stats() ReaderStats {
+	messages := atomic.SwapInt64(&r.messageCount, 0)
+	bytes := atomic.SwapInt64(&r.byteCount, 0)
+	rebalances := atomic.SwapInt64(&r.rebalanceCount, 0)
+	timeouts := atomic.SwapInt64(&r.timeoutCount, 0)
+	errs := atomic.SwapInt64(&r.errorCount, 0)
+	fetches := atomic.SwapInt64(&r.fetchCount, 0)
+	fetchMsgs := atomic.SwapInt64(&r.fetchMsgSum, 0)
+	fetchBytes := atomic.SwapInt64(&r.fetchByteSum, 0)
+
+	var fetchSizeAvg, fetchBytesAvg float64
+	if fetches > 0 {
+		fetchSizeAvg = float64(fetchMsgs) / float64(fetches)
+		fetchBytesAvg = float64(fetchBytes) / float64(fetches)
+	}
+
+	offset := atomic.LoadInt64(&r.lastOffset)
+	hwm := atomic.LoadInt64(&r.lastHighWater)
+
+	return ReaderStats{
+		Messages:      messages,
+		Bytes:         bytes,
+		Rebalances:    rebalances,
+		Timeouts:      timeouts,
+		Errors:        errs,
+		FetchSize:     SummaryStat{Avg: fetchSizeAvg},
+		FetchBytes:    SummaryStat{Avg: fetchBytesAvg},
+		Offset:        offset,
+		Lag:           hwm - offset,
+		QueueLength:   int64(len(r.messages)),
+		QueueCapacity: int64(cap(r.messages)),
+	}
+}
+
+// Close stops this Reader: in group mode it cancels every partition
+// goroutine, leaves the consumer group, and waits for them to return;
+// Close is always safe to call more than once.
+func (r *Reader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		if r.cancel != nil {
+			r.cancel()
+		}
+		r.wg.Wait()
+		r.rebalancer.Wait()
+		r.supervisor.stopped()
+		if closer, ok := r.transport.(interface{ Close() error }); ok {
+			closer.Close()
+		}
+	})
+	return nil
+}
+
+// coordinator dials one of this Reader's brokers and returns a Conn to the
+// coordinator of its consumer group.
+func (r *Reader) coordinator(ctx context.Context) (*Conn, error) {
+	if r.config.GroupID == "" {
+		return nil, fmt.Errorf("kafka: reader is not using consumer groups")
+	}
+	return dialGroupCoordinator(ctx, r.config, r.config.GroupID)
+}
+
+const groupSessionTimeout = 30 * time.Second
+
+// runGroup drives this Reader's membership of its consumer group for as
+// long as ctx is not done: it joins (or rejoins, after a rebalance or a
+// transient coordinator failure), computes or receives this round's
+// partition assignment, starts/stops the per-partition fetch goroutines
+// through Rebalancer to match, and heartbeats until the coordinator signals
+// a rebalance is needed.
+func (r *Reader) runGroup(ctx context.Context) {
+	defer r.wg.Done()
+
+	memberID := ""
+	for ctx.Err() == nil {
+		conn, join, err := r.joinAndSync(ctx, memberID)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return
+			}
+			var kerr Error
+			if errors.As(err, &kerr) && kerr.Code == errCodeUnknownMemberID {
+				memberID = ""
+			}
+			delay := r.supervisor.failed()
+			if !sleepCtx(ctx, delay) {
+				return
+			}
+			continue
+		}
+		memberID = join.memberID
+		r.supervisor.succeeded()
+		atomic.AddInt64(&r.rebalanceCount, 1)
+
+		rejoin := r.heartbeatLoop(ctx, conn, memberID, join.generationID)
+		conn.Close()
+		if !rejoin {
+			r.rebalancer.Revoke(r.config.Topic, nil)
+			return
+		}
+	}
+}
+
+// joinAndSync performs one JoinGroup/SyncGroup round trip and applies the
+// resulting assignment through Rebalancer, returning the coordinator Conn
+// so the caller can heartbeat on it without dialing again.
+func (r *Reader) joinAndSync(ctx context.Context, memberID string) (*Conn, joinGroupResult, error) {
+	conn, err := r.coordinator(ctx)
+	if err != nil {
+		return nil, joinGroupResult{}, err
+	}
+
+	previous := toInt32Slice(r.rebalancer.membership.owned(r.config.Topic))
+	subscription := encodeConsumerProtocolSubscription(consumerProtocolSubscription{
+		topics:   []string{r.config.Topic},
+		userData: encodeConsumerProtocolAssignment(consumerProtocolAssignment{partitions: map[string][]int32{r.config.Topic: previous}}),
+	})
+
+	join, err := conn.joinGroup(r.config.GroupID, memberID, r.config.RebalanceStrategy.String(), subscription, groupSessionTimeout)
+	if err != nil {
+		conn.Close()
+		return nil, joinGroupResult{}, err
+	}
+
+	var assignments map[string][]byte
+	if join.isLeader() {
+		partitions, err := r.transport.ReadPartitions(ctx, r.config.Topic)
+		if err != nil {
+			conn.Close()
+			return nil, joinGroupResult{}, err
+		}
+
+		previousOwners := make(map[string][]int32, len(join.members))
+		for _, m := range join.members {
+			if prev, err := decodeConsumerProtocolAssignment(m.subscription.userData); err == nil {
+				previousOwners[m.id] = prev.partitions[r.config.Topic]
+			}
+		}
+
+		perMember := assignPartitions(r.config.RebalanceStrategy, r.config.Topic, len(partitions), join.members, previousOwners)
+		assignments = make(map[string][]byte, len(perMember))
+		for id, parts := range perMember {
+			assignments[id] = encodeConsumerProtocolAssignment(consumerProtocolAssignment{partitions: map[string][]int32{r.config.Topic: parts}})
+		}
+	}
+
+	myAssignment, err := conn.syncGroup(r.config.GroupID, join.memberID, join.generationID, assignments)
+	if err != nil {
+		conn.Close()
+		return nil, joinGroupResult{}, err
+	}
+
+	decoded, err := decodeConsumerProtocolAssignment(myAssignment)
+	if err != nil {
+		conn.Close()
+		return nil, joinGroupResult{}, err
+	}
+	desired := toIntSlice(decoded.partitions[r.config.Topic])
+
+	if r.config.RebalanceStrategy.cooperative() {
+		r.rebalancer.Revoke(r.config.Topic, desired)
+	} else {
+		r.rebalancer.Revoke(r.config.Topic, nil)
+	}
+	r.rebalancer.Assign(ctx, r.config.Topic, desired, r.consumePartition)
+
+	return conn, join, nil
+}
+
+// heartbeatLoop sends a Heartbeat request every third of the session
+// timeout until ctx is done (returns false) or a Heartbeat request fails for
+// any reason, such as errCodeRebalanceInProgress or a generation mismatch
+// (returns true, asking the caller to rejoin). A failure with
+// errCodeUnknownMemberID reports the member's partitions through
+// Rebalancer.Lost before returning.
+func (r *Reader) heartbeatLoop(ctx context.Context, conn *Conn, memberID string, generationID int32) (rejoin bool) {
+	ticker := time.NewTicker(groupSessionTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.leaveGroup(r.config.GroupID, memberID)
+			return false
+		case <-ticker.C:
+			if err := conn.heartbeat(r.config.GroupID, memberID, generationID); err != nil {
+				// Any heartbeat failure - an explicit rebalance-in-progress
+				// error as much as a network blip - is recovered the same
+				// way: rejoin the group from scratch. An unknown member id
+				// means the coordinator already evicted this member.
+				var kerr Error
+				if errors.As(err, &kerr) && kerr.Code == errCodeUnknownMemberID {
+					r.rebalancer.Lost(r.config.Topic)
+				}
+				return true
+			}
+		}
+	}
+}
+
+// consumePartition is the per-partition worker Rebalancer.Assign starts: it
+// fetches partition's committed offset once, then feeds messages it reads
+// from it into r.messages until ctx is done (the partition is revoked or
+// the Reader is closed).
+func (r *Reader) consumePartition(ctx context.Context, partition int) {
+	offset := int64(0)
+	if conn, err := r.coordinator(ctx); err == nil {
+		if committed, _, _, err := conn.fetchCommittedOffset(r.config.GroupID, r.config.Topic, partition); err == nil {
+			offset = committed
+		}
+		conn.Close()
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		msgs, _, err := r.transport.Fetch(ctx, r.config.Topic, partition, offset, r.config.MaxWait)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return
+			}
+			atomic.AddInt64(&r.errorCount, 1)
+			delay := r.supervisor.failed()
+			if !sleepCtx(ctx, delay) {
+				return
+			}
+			continue
+		}
+		r.supervisor.succeeded()
+		if len(msgs) == 0 {
+			atomic.AddInt64(&r.timeoutCount, 1)
+			continue
+		}
+		for _, msg := range msgs {
+			select {
+			case r.messages <- msg:
+				offset = msg.Offset + 1
+				atomic.AddInt64(&r.messageCount, 1)
+				atomic.AddInt64(&r.byteCount, int64(len(msg.Key)+len(msg.Value)))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// sleepCtx waits for d or ctx to be done, whichever comes first, and
+// reports whether the wait completed normally (as opposed to being cut
+// short by ctx).
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func toInt32Slice(in []int) []int32 {
+	out := make([]int32, len(in))
+	for i, v := range in {
+		out[i] = int32(v)
+	}
+	return out
+}
+
+func toIntSlice(in []int32) []int {
+	out := make([]int, len(in))
+	for i, v := range in {
+		out[i] = int(v)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kafka "github.com/supplyon/kafka-go"
+)
+
+// flakyTransport fails the first failCount Fetch calls, then serves msgs.
+// It exists to prove Reader actually drives ConnSupervisor off the fetch
+// loop: without that wiring, a Reader would either return the first Fetch
+// error straight to ReadMessage or spin on it without backing off.
+type flakyTransport struct {
+	failCount int
+	msgs      []kafka.Message
+
+	attempts int
+}
+
+func (f *flakyTransport) ReadPartitions(ctx context.Context, topic string) ([]kafka.Partition, error) {
+	return []kafka.Partition{{Topic: topic, ID: 0}}, nil
+}
+
+func (f *flakyTransport) Fetch(ctx context.Context, topic string, partition int, offset int64, maxWait time.Duration) ([]kafka.Message, int64, error) {
+	f.attempts++
+	if f.attempts <= f.failCount {
+		return nil, 0, errors.New("simulated broker outage")
+	}
+	if offset >= int64(len(f.msgs)) {
+		return nil, int64(len(f.msgs)), nil
+	}
+	m := f.msgs[offset]
+	return []kafka.Message{m}, int64(len(f.msgs)), nil
+}
+
+func (f *flakyTransport) Produce(ctx context.Context, topic string, partition int, msgs ...kafka.Message) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (f *flakyTransport) Close() error { return nil }
+
+// TestReaderSupervisorRecoversFromFailures verifies that a Reader backed by
+// a Transport that fails a few times before recovering ends up back in
+// ReaderStateRunning, having retried rather than surfaced the first
+// failure to the caller.
+func TestReaderSupervisorRecoversFromFailures(t *testing.T) {
+	transport := &flakyTransport{
+		failCount: 3,
+		msgs:      []kafka.Message{{Value: []byte("first")}},
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Topic: "test-topic",
+		Backoff: &kafka.ExponentialBackoff{
+			Init: time.Millisecond,
+			Max:  5 * time.Millisecond,
+		},
+		Transport: transport,
+	})
+	defer r.Close()
+
+	if got := r.State(); got != kafka.ReaderStateConnecting {
+		t.Fatalf("expected a fresh Reader to start ReaderStateConnecting, got %s", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg, err := r.ReadMessage(ctx)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if string(msg.Value) != "first" {
+		t.Fatalf("got message %q, want %q", msg.Value, "first")
+	}
+	if transport.attempts <= transport.failCount {
+		t.Fatalf("expected more than %d Fetch attempts, got %d", transport.failCount, transport.attempts)
+	}
+	if got := r.State(); got != kafka.ReaderStateRunning {
+		t.Fatalf("expected ReaderStateRunning after a successful fetch, got %s", got)
+	}
+}
+
+// TestReaderStateStoppedAfterClose verifies Close transitions the Reader to
+// ReaderStateStopped, matching what ReaderState's doc promises.
+func TestReaderStateStoppedAfterClose(t *testing.T) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Topic:     "test-topic",
+		Transport: &flakyTransport{},
+	})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.State(); got != kafka.ReaderStateStopped {
+		t.Fatalf("expected ReaderStateStopped after Close, got %s", got)
+	}
+}
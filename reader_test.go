@@ -1,4 +1,4 @@
-package kafka
+package kafka_test
 
 import (
 	"context"
@@ -6,6 +6,9 @@ import (
 	"strconv"
 	"testing"
 	"time"
+
+	kafka "github.com/supplyon/kafka-go"
+	"github.com/supplyon/kafka-go/kafkatest"
 )
 
 func TestReader(t *testing.T) {
@@ -13,7 +16,7 @@ func TestReader(t *testing.T) {
 
 	tests := []struct {
 		scenario string
-		function func(*testing.T, context.Context, *Reader)
+		function func(*testing.T, context.Context, *kafkatest.Tester, *kafka.Reader)
 	}{
 		{
 			scenario: "calling Read with a context that has been canceled should return an error",
@@ -44,18 +47,19 @@ func TestReader(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
 
-			r := NewReader(ReaderConfig{
-				Brokers: []string{"localhost:9092"},
-				Topic:   makeTopic(),
-				MaxWait: 500 * time.Millisecond,
+			tt := kafkatest.NewTester(t)
+			r := kafka.NewReader(kafka.ReaderConfig{
+				Topic:     "test-topic",
+				MaxWait:   500 * time.Millisecond,
+				Transport: tt.Transport(),
 			})
 			defer r.Close()
-			testFunc(t, ctx, r)
+			testFunc(t, ctx, tt, r)
 		})
 	}
 }
 
-func testReaderReadCanceled(t *testing.T, ctx context.Context, r *Reader) {
+func testReaderReadCanceled(t *testing.T, ctx context.Context, tt *kafkatest.Tester, r *kafka.Reader) {
 	ctx, cancel := context.WithCancel(ctx)
 	cancel()
 
@@ -64,9 +68,9 @@ func testReaderReadCanceled(t *testing.T, ctx context.Context, r *Reader) {
 	}
 }
 
-func testReaderReadMessages(t *testing.T, ctx context.Context, r *Reader) {
+func testReaderReadMessages(t *testing.T, ctx context.Context, tt *kafkatest.Tester, r *kafka.Reader) {
 	const N = 1000
-	prepareReader(t, ctx, r, makeTestSequence(N)...)
+	prepareReader(t, tt, r, makeTestSequence(N)...)
 
 	for i := 0; i != N; i++ {
 		m, err := r.ReadMessage(ctx)
@@ -82,7 +86,7 @@ func testReaderReadMessages(t *testing.T, ctx context.Context, r *Reader) {
 	}
 }
 
-func testReaderSetInvalidOffset(t *testing.T, ctx context.Context, r *Reader) {
+func testReaderSetInvalidOffset(t *testing.T, ctx context.Context, tt *kafkatest.Tester, r *kafka.Reader) {
 	r.SetOffset(42)
 
 	_, err := r.ReadMessage(ctx)
@@ -91,9 +95,9 @@ func testReaderSetInvalidOffset(t *testing.T, ctx context.Context, r *Reader) {
 	}
 }
 
-func testReaderSetRandomOffset(t *testing.T, ctx context.Context, r *Reader) {
+func testReaderSetRandomOffset(t *testing.T, ctx context.Context, tt *kafkatest.Tester, r *kafka.Reader) {
 	const N = 10
-	prepareReader(t, ctx, r, makeTestSequence(N)...)
+	prepareReader(t, tt, r, makeTestSequence(N)...)
 
 	for i := 0; i != 2*N; i++ {
 		offset := rand.Intn(N)
@@ -111,24 +115,27 @@ func testReaderSetRandomOffset(t *testing.T, ctx context.Context, r *Reader) {
 	}
 }
 
-func makeTestSequence(n int) []Message {
-	msgs := make([]Message, n)
+func makeTestSequence(n int) []kafka.Message {
+	msgs := make([]kafka.Message, n)
 	for i := 0; i != n; i++ {
-		msgs[i] = Message{
+		msgs[i] = kafka.Message{
 			Value: []byte(strconv.Itoa(i)),
 		}
 	}
 	return msgs
 }
 
-func prepareReader(t *testing.T, ctx context.Context, r *Reader, msgs ...Message) {
+// prepareReader writes msgs directly to r's topic/partition through tt's
+// in-memory Broker, standing in for dialing a live leader and calling
+// Conn.WriteMessages against it.
+func prepareReader(t *testing.T, tt *kafkatest.Tester, r *kafka.Reader, msgs ...kafka.Message) {
+	t.Helper()
+
 	config := r.Config()
-	conn, err := DialLeader(ctx, "tcp", "localhost:9092", config.Topic, config.Partition)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer conn.Close()
-	if _, err := conn.WriteMessages(msgs...); err != nil {
-		t.Fatal(err)
+	broker := tt.Broker()
+	for _, m := range msgs {
+		if _, err := broker.Produce(config.Topic, config.Partition, m.Key, m.Value); err != nil {
+			t.Fatal(err)
+		}
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,180 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+)
+
+// partitionWorkers runs one goroutine per partition a Reader currently
+// owns, and is the piece that makes cooperative rebalancing worthwhile:
+// stop only cancels the single partition asked for, so every other
+// partition's goroutine keeps consuming uninterrupted across a rebalance.
+type partitionWorkers struct {
+	mutex   sync.Mutex
+	cancels map[string]map[int]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newPartitionWorkers() *partitionWorkers {
+	return &partitionWorkers{cancels: make(map[string]map[int]context.CancelFunc)}
+}
+
+// start launches run on its own goroutine for topic/partition, deriving
+// its context from ctx so that canceling ctx (e.g. Reader.Close) stops
+// every partition at once, while stop cancels just this one.
+func (pw *partitionWorkers) start(ctx context.Context, topic string, partition int, run func(ctx context.Context)) {
+	workerCtx, cancel := context.WithCancel(ctx)
+
+	pw.mutex.Lock()
+	if pw.cancels[topic] == nil {
+		pw.cancels[topic] = make(map[int]context.CancelFunc)
+	}
+	pw.cancels[topic][partition] = cancel
+	pw.mutex.Unlock()
+
+	pw.wg.Add(1)
+	go func() {
+		defer pw.wg.Done()
+		run(workerCtx)
+	}()
+}
+
+// stop cancels and forgets the goroutine running topic/partition, if any.
+// It does not wait for the goroutine to actually exit; callers that need
+// that guarantee should wait on a signal of their own from inside run, or
+// call wait after stopping every partition they care about.
+func (pw *partitionWorkers) stop(topic string, partition int) {
+	pw.mutex.Lock()
+	cancel, ok := pw.cancels[topic][partition]
+	if ok {
+		delete(pw.cancels[topic], partition)
+	}
+	pw.mutex.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// wait blocks until every started goroutine, stopped or not, has returned.
+func (pw *partitionWorkers) wait() {
+	pw.wg.Wait()
+}
+
+// Rebalancer drives a topic's consumer group membership through a
+// rebalance using RebalanceStrategy and RebalanceCallbacks, and owns the
+// per-partition goroutines consuming each partition the group assigns it.
+//
+// A rebalance happens in two steps: Revoke is called with the target
+// assignment and stops whatever partitions are leaving; Assign then starts
+// whatever partitions the member has gained. For every RebalanceStrategy
+// other than RebalanceStrategyCooperativeSticky, Revoke stops the member's
+// entire current assignment and Assign starts the entire new one, matching
+// the eager protocol's stop-the-world semantics. Reader calls Revoke and
+// then Assign after a single JoinGroup/SyncGroup round trip, for every
+// strategy; it runs no second round, so with
+// RebalanceStrategyCooperativeSticky a partition that moves is handed to
+// its new owner in the same round in which its previous owner stops it.
+type Rebalancer struct {
+	strategy   RebalanceStrategy
+	callbacks  RebalanceCallbacks
+	membership *membershipStatus
+	workers    *partitionWorkers
+}
+
+// NewRebalancer returns a Rebalancer with no partitions owned yet.
+func NewRebalancer(strategy RebalanceStrategy, callbacks RebalanceCallbacks) *Rebalancer {
+	return &Rebalancer{
+		strategy:   strategy,
+		callbacks:  callbacks,
+		membership: newMembershipStatus(),
+		workers:    newPartitionWorkers(),
+	}
+}
+
+// Revoke is phase one of a rebalance. desired is the full set of
+// partitions of topic this member will own once the rebalance completes.
+// With RebalanceStrategyCooperativeSticky, only the partitions the member
+// currently owns but that are absent from desired are stopped and
+// reported to RebalanceCallbacks.OnPartitionsRevoked; every partition
+// present in both sets keeps running. Every other strategy stops the
+// member's entire current assignment for topic, to be fully replaced by
+// Assign. Revoke returns the partitions it stopped.
+func (rb *Rebalancer) Revoke(topic string, desired []int) []int {
+	var revoked []int
+	if rb.strategy.cooperative() {
+		keep := intersectPartitions(rb.membership.owned(topic), desired)
+		_, revoked = rb.membership.applyCooperative(topic, keep)
+	} else {
+		_, revoked = rb.membership.applyEager(topic, nil)
+	}
+
+	for _, p := range revoked {
+		rb.workers.stop(topic, p)
+	}
+	rb.callbacks.revoked(topic, revoked)
+	return revoked
+}
+
+// Assign is phase two of a rebalance, called once the member has rejoined
+// the group and been handed its new assignment. It starts run on its own
+// goroutine for every partition of topic that Revoke did not already
+// leave running, and reports them to
+// RebalanceCallbacks.OnPartitionsAssigned. run is expected to loop until
+// ctx is done, which happens either when the partition is later revoked
+// or when ctx passed to Assign is canceled (e.g. Reader.Close).
+func (rb *Rebalancer) Assign(ctx context.Context, topic string, desired []int, run func(ctx context.Context, partition int)) []int {
+	var assigned []int
+	if rb.strategy.cooperative() {
+		assigned, _ = rb.membership.applyCooperative(topic, desired)
+	} else {
+		assigned, _ = rb.membership.applyEager(topic, desired)
+	}
+
+	for _, p := range assigned {
+		partition := p
+		rb.workers.start(ctx, topic, partition, func(workerCtx context.Context) { run(workerCtx, partition) })
+	}
+	rb.callbacks.assigned(topic, assigned)
+	return assigned
+}
+
+// Lost reports partitions of topic as lost rather than revoked: the
+// member was evicted from the group (session expiry, etc.) before it
+// could revoke cleanly, so the partitions may already be owned elsewhere
+// and must not be used to commit offsets. Lost stops their goroutines the
+// same way Revoke does, but reports them through
+// RebalanceCallbacks.OnPartitionsLost instead.
+func (rb *Rebalancer) Lost(topic string) []int {
+	lost := rb.membership.owned(topic)
+	_, _ = rb.membership.applyEager(topic, nil)
+	for _, p := range lost {
+		rb.workers.stop(topic, p)
+	}
+	rb.callbacks.lost(topic, lost)
+	return lost
+}
+
+// Wait blocks until every partition goroutine this Rebalancer has started,
+// stopped or not, has returned. Callers typically cancel the context
+// passed to Assign (or each Revoke/Lost partition's owning context) before
+// calling Wait, so that every goroutine is on its way out.
+func (rb *Rebalancer) Wait() {
+	rb.workers.wait()
+}
+
+// intersectPartitions returns the partitions present in both owned and
+// desired, preserving no particular order.
+func intersectPartitions(owned, desired []int) []int {
+	desiredSet := make(map[int]struct{}, len(desired))
+	for _, p := range desired {
+		desiredSet[p] = struct{}{}
+	}
+	var keep []int
+	for _, p := range owned {
+		if _, ok := desiredSet[p]; ok {
+			keep = append(keep, p)
+		}
+	}
+	return keep
+}
@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runUntilDone starts a fake per-partition consumer that just blocks until
+// its context is canceled, recording that it ran and that it stopped.
+func runUntilDone(t *testing.T, started, stopped *sync.Map) func(ctx context.Context, partition int) {
+	return func(ctx context.Context, partition int) {
+		started.Store(partition, true)
+		<-ctx.Done()
+		stopped.Store(partition, true)
+	}
+}
+
+func sortedKeys(m *sync.Map) []int {
+	var keys []int
+	m.Range(func(k, _ any) bool {
+		keys = append(keys, k.(int))
+		return true
+	})
+	sort.Ints(keys)
+	return keys
+}
+
+func TestRebalancerCooperativeKeepsUnaffectedPartitionsRunning(t *testing.T) {
+	var revoked, assigned [][]int
+	var mu sync.Mutex
+	callbacks := RebalanceCallbacks{
+		OnPartitionsRevoked: func(topic string, partitions []int) {
+			mu.Lock()
+			revoked = append(revoked, append([]int(nil), partitions...))
+			mu.Unlock()
+		},
+		OnPartitionsAssigned: func(topic string, partitions []int) {
+			mu.Lock()
+			assigned = append(assigned, append([]int(nil), partitions...))
+			mu.Unlock()
+		},
+	}
+
+	rb := NewRebalancer(RebalanceStrategyCooperativeSticky, callbacks)
+
+	var started, stopped sync.Map
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Initial assignment: partitions 0, 1, 2.
+	rb.Revoke("orders", []int{0, 1, 2})
+	rb.Assign(ctx, "orders", []int{0, 1, 2}, runUntilDone(t, &started, &stopped))
+
+	waitForPartitions(t, &started, []int{0, 1, 2})
+
+	// Rebalance moves partition 2 away and gives us partition 3 instead;
+	// 0 and 1 must never be touched.
+	gotRevoked := rb.Revoke("orders", []int{0, 1, 3})
+	if !equalInts(gotRevoked, []int{2}) {
+		t.Fatalf("expected only partition 2 revoked, got %v", gotRevoked)
+	}
+	waitForPartitions(t, &stopped, []int{2})
+	if _, ok := stopped.Load(0); ok {
+		t.Fatal("partition 0 was stopped during a cooperative rebalance that kept it")
+	}
+	if _, ok := stopped.Load(1); ok {
+		t.Fatal("partition 1 was stopped during a cooperative rebalance that kept it")
+	}
+
+	gotAssigned := rb.Assign(ctx, "orders", []int{0, 1, 3}, runUntilDone(t, &started, &stopped))
+	if !equalInts(gotAssigned, []int{3}) {
+		t.Fatalf("expected only partition 3 newly assigned, got %v", gotAssigned)
+	}
+	waitForPartitionStarted(t, &started, 3)
+	if !equalInts(rb.membership.owned("orders"), []int{0, 1, 3}) {
+		t.Fatalf("expected current ownership to be [0 1 3], got %v", rb.membership.owned("orders"))
+	}
+
+	cancel()
+	rb.Wait()
+}
+
+func TestRebalancerEagerStopsEverything(t *testing.T) {
+	rb := NewRebalancer(RebalanceStrategyRange, RebalanceCallbacks{})
+
+	var started, stopped sync.Map
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rb.Revoke("orders", []int{0, 1})
+	rb.Assign(ctx, "orders", []int{0, 1}, runUntilDone(t, &started, &stopped))
+	waitForPartitions(t, &started, []int{0, 1})
+
+	revoked := rb.Revoke("orders", []int{0, 1, 2})
+	if !equalInts(revoked, []int{0, 1}) {
+		t.Fatalf("eager strategy should revoke the entire current assignment, got %v", revoked)
+	}
+	waitForPartitions(t, &stopped, []int{0, 1})
+
+	assigned := rb.Assign(ctx, "orders", []int{0, 1, 2}, runUntilDone(t, &started, &stopped))
+	if !equalInts(assigned, []int{0, 1, 2}) {
+		t.Fatalf("eager strategy should reassign everything, got %v", assigned)
+	}
+
+	cancel()
+	rb.Wait()
+}
+
+func waitForPartitionStarted(t *testing.T, m *sync.Map, partition int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := m.Load(partition); ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for partition %d to start", partition)
+}
+
+func waitForPartitions(t *testing.T, m *sync.Map, want []int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if equalInts(sortedKeys(m), want) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for partitions %v, got %v", want, sortedKeys(m))
+}
+
+func equalInts(a, b []int) bool {
+	a, b = append([]int(nil), a...), append([]int(nil), b...)
+	sort.Ints(a)
+	sort.Ints(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
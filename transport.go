@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Transport is the seam Reader and Writer use to read and write messages,
+// kept separate from the rest of Conn's broker-protocol surface
+// (coordinator lookups, group membership, offset import/export) so that
+// tests can swap in an in-memory implementation without a transport
+// interface change rippling through every Conn consumer. dialedTransport
+// is the production implementation, dialing real brokers over TCP; the
+// kafkatest package provides one backed by its in-memory Broker.
+type Transport interface {
+	// ReadPartitions returns the partitions of topic.
+	ReadPartitions(ctx context.Context, topic string) ([]Partition, error)
+
+	// Fetch returns the messages available in topic/partition at or after
+	// offset, waiting up to maxWait if none are yet available, along with
+	// the partition's current high water mark.
+	Fetch(ctx context.Context, topic string, partition int, offset int64, maxWait time.Duration) (messages []Message, highWaterMark int64, err error)
+
+	// Produce writes msgs to topic/partition and returns the number of
+	// bytes written.
+	Produce(ctx context.Context, topic string, partition int, msgs ...Message) (int, error)
+
+	// Close releases any connections the Transport is holding open.
+	Close() error
+}
+
+// dialedTransport is the default Transport: it dials brokers lazily,
+// caching one Conn per topic/partition it has been asked to read from or
+// write to, and dials fresh for ReadPartitions since that only needs a
+// connection to any broker.
+type dialedTransport struct {
+	brokers []string
+	dialer  *Dialer
+
+	mutex sync.Mutex
+	conns map[string]*Conn // "topic/partition" -> Conn dialed to its leader
+}
+
+func newDialedTransport(brokers []string, dialer *Dialer) *dialedTransport {
+	if dialer == nil {
+		dialer = DefaultDialer
+	}
+	return &dialedTransport{brokers: brokers, dialer: dialer, conns: make(map[string]*Conn)}
+}
+
+func (t *dialedTransport) bootstrap() string {
+	if len(t.brokers) == 0 {
+		return ""
+	}
+	return t.brokers[0]
+}
+
+func (t *dialedTransport) ReadPartitions(ctx context.Context, topic string) ([]Partition, error) {
+	addr := t.bootstrap()
+	if addr == "" {
+		return nil, fmt.Errorf("kafka: no brokers configured")
+	}
+	conn, err := t.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ReadPartitions(topic)
+}
+
+func (t *dialedTransport) connFor(ctx context.Context, topic string, partition int) (*Conn, error) {
+	key := fmt.Sprintf("%s/%d", topic, partition)
+
+	t.mutex.Lock()
+	conn, ok := t.conns[key]
+	t.mutex.Unlock()
+	if ok {
+		return conn, nil
+	}
+
+	addr := t.bootstrap()
+	if addr == "" {
+		return nil, fmt.Errorf("kafka: no brokers configured")
+	}
+	conn, err := t.dialer.DialLeader(ctx, "tcp", addr, topic, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	if existing, ok := t.conns[key]; ok {
+		t.mutex.Unlock()
+		conn.Close()
+		return existing, nil
+	}
+	t.conns[key] = conn
+	t.mutex.Unlock()
+	return conn, nil
+}
+
+func (t *dialedTransport) Fetch(ctx context.Context, topic string, partition int, offset int64, maxWait time.Duration) ([]Message, int64, error) {
+	conn, err := t.connFor(ctx, topic, partition)
+	if err != nil {
+		return nil, 0, err
+	}
+	return conn.fetch(ctx, offset, maxWait)
+}
+
+func (t *dialedTransport) Produce(ctx context.Context, topic string, partition int, msgs ...Message) (int, error) {
+	conn, err := t.connFor(ctx, topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	return conn.WriteMessages(msgs...)
+}
+
+func (t *dialedTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var firstErr error
+	for _, conn := range t.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	t.conns = make(map[string]*Conn)
+	return firstErr
+}
@@ -0,0 +1,154 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// WriterConfig configures a Writer.
+type WriterConfig struct {
+	// Brokers is the list of broker addresses used to bootstrap the
+	// connection to the cluster.
+	Brokers []string
+
+	// Topic is the topic every message is written to, unless a message
+	// sets its own Topic field.
+	Topic string
+
+	// Dialer is used to establish every connection this Writer opens.
+	// Defaults to DefaultDialer.
+	Dialer *Dialer
+
+	// Transport overrides how this Writer writes messages. Defaults to a
+	// Transport that dials Brokers with Dialer; tests can substitute an
+	// in-memory implementation such as kafkatest's.
+	Transport Transport
+}
+
+// Writer writes messages to a Kafka topic, choosing a partition for each
+// message with a simple round-robin counter.
+type Writer struct {
+	config    WriterConfig
+	transport Transport
+
+	next uint64 // round-robin partition counter
+
+	writeCount   int64
+	messageCount int64
+	byteCount    int64
+	errorCount   int64
+	retryCount   int64
+
+	partitionsMutex sync.Mutex
+	partitionCount  map[string]int
+}
+
+// NewWriter returns a Writer configured by config.
+func NewWriter(config WriterConfig) *Writer {
+	if config.Dialer == nil {
+		config.Dialer = DefaultDialer
+	}
+	transport := config.Transport
+	if transport == nil {
+		transport = newDialedTransport(config.Brokers, config.Dialer)
+	}
+	return &Writer{config: config, transport: transport, partitionCount: make(map[string]int)}
+}
+
+// WriteMessages writes every message in msgs, returning the first error
+// encountered. Messages that leave Topic empty are written to w's
+// configured Topic.
+func (w *Writer) WriteMessages(ctx context.Context, msgs ...Message) error {
+	atomic.AddInt64(&w.writeCount, 1)
+
+	for _, m := range msgs {
+		topic := m.Topic
+		if topic == "" {
+			topic = w.config.Topic
+		}
+
+		partition, err := w.choosePartition(ctx, topic)
+		if err != nil {
+			atomic.AddInt64(&w.errorCount, 1)
+			return fmt.Errorf("kafka: (*Writer).WriteMessages: %w", err)
+		}
+
+		n, err := w.transport.Produce(ctx, topic, partition, m)
+		if err != nil {
+			atomic.AddInt64(&w.errorCount, 1)
+			return fmt.Errorf("kafka: (*Writer).WriteMessages: %w", err)
+		}
+		atomic.AddInt64(&w.messageCount, 1)
+		atomic.AddInt64(&w.byteCount, int64(n))
+	}
+	return nil
+}
+
+// choosePartition returns the next partition of topic to write to,
+// round-robin, caching the partition count per topic so repeated writes
+// don't re-fetch metadata every time.
+func (w *Writer) choosePartition(ctx context.Context, topic string) (int, error) {
+	w.partitionsMutex.Lock()
+	count, ok := w.partitionCount[topic]
+	w.partitionsMutex.Unlock()
+
+	if !ok {
+		partitions, err := w.transport.ReadPartitions(ctx, topic)
+		if err != nil {
+			return 0, err
+		}
+		count = len(partitions)
+		if count == 0 {
+			count = 1
+		}
+		w.partitionsMutex.Lock()
+		w.partitionCount[topic] = count
+		w.partitionsMutex.Unlock()
+	}
+
+	n := atomic.AddUint64(&w.next, 1) - 1
+	return int(n % uint64(count)), nil
+}
+
+// WriterStats are the counters and gauges tracked by a Writer. Writes,
+// Messages, Bytes, Errors, and Retries accumulate since the previous call
+// to Stats and are reset by it, so each call reports only what happened
+// since the one before.
+type WriterStats struct {
+	Writes   int64
+	Messages int64
+	Bytes    int64
+	Errors   int64
+	Retries  int64
+
+	WriteTime DurationStat
+	WaitTime  DurationStat
+
+	BatchSize  SummaryStat
+	BatchBytes SummaryStat
+
+	QueueLength   int64
+	QueueCapacity int64
+}
+
+// Stats returns this Writer's counters and gauges, resetting the
+// accumulating fields back to zero.
+func (w *Writer) Stats() WriterStats {
+	return WriterStats{
+		Writes:   atomic.SwapInt64(&w.writeCount, 0),
+		Messages: atomic.SwapInt64(&w.messageCount, 0),
+		Bytes:    atomic.SwapInt64(&w.byteCount, 0),
+		Errors:   atomic.SwapInt64(&w.errorCount, 0),
+		Retries:  atomic.SwapInt64(&w.retryCount, 0),
+	}
+}
+
+// Close releases the connections this Writer is holding open.
+func (w *Writer) Close() error {
+	if closer, ok := w.transport.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}